@@ -0,0 +1,77 @@
+package feed
+
+import "sync"
+
+// Aggregator remembers which posts it has already handed out, so calling Poll
+// over and over only ever returns posts it hasn't seen before.
+type Aggregator struct {
+	fetcher *Fetcher
+
+	mu   sync.Mutex
+	seen SeenSet
+}
+
+func NewAggregator(opts ...Option) *Aggregator {
+	f := NewFetcher(opts...)
+	var seen SeenSet = make(mapSet)
+	if f.opts.seenSet != nil {
+		seen = f.opts.seenSet
+	}
+
+	return &Aggregator{fetcher: f, seen: seen}
+}
+
+// Poll fetches every feed and returns only the posts that no earlier Poll
+// (or Import) has already covered, newest first.
+func (a *Aggregator) Poll(feeds []string) []BlogPost {
+	posts := a.fetcher.FetchAllFeeds(feeds)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var newPosts []BlogPost
+	for _, post := range posts {
+		key := seenKey(post)
+		if a.seen.Has(key) {
+			continue
+		}
+		a.seen.Add(key)
+		newPosts = append(newPosts, post)
+	}
+
+	return newPosts
+}
+
+// Export dumps the seen-set so it can be saved & handed back to Import after a restart.
+// A SeenSet that can't list its keys (like BloomSet) exports nothing, save it directly instead.
+func (a *Aggregator) Export() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if set, ok := a.seen.(mapSet); ok {
+		return set.keys()
+	}
+	return nil
+}
+
+// Import marks keys from an earlier Export as seen. It adds to the current set rather than replacing it.
+func (a *Aggregator) Import(keys []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, key := range keys {
+		a.seen.Add(key)
+	}
+}
+
+// GUID first, then the link in canonical form, and if a feed gives us
+// neither we make do with title + date
+func seenKey(post BlogPost) string {
+	if post.GUID != "" {
+		return post.GUID
+	}
+	if link, err := CanonicalizeLink(post.Link); err == nil {
+		return link
+	}
+	return post.Title + "|" + post.PublishedAt.String()
+}
@@ -0,0 +1,95 @@
+package feed
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RawAuthor covers RSS's <author>email (Name)</author>, atom's
+// <author><name/><uri/><email/></author> & plain itunes:author text
+type RawAuthor struct {
+	Text  string `xml:",chardata"`
+	Name  string `xml:"name"`
+	URI   string `xml:"uri"`
+	Email string `xml:"email"`
+}
+
+// Author is one credited author. Only Name is at all common, the rest is
+// whatever the feed bothered to include.
+type Author struct {
+	Name string
+	// URI is a profile or homepage
+	URI   string
+	Email string
+}
+
+// what we show when there's only one string to show
+func (a Author) display() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return a.Email
+}
+
+// "jane@example.com (Jane Doe)", which is what the RSS spec actually asks for
+var rssAuthorRegex = regexp.MustCompile(`^(\S+@\S+)\s*\((.+)\)$`)
+
+func (raw RawAuthor) author() Author {
+	a := Author{
+		Name:  strings.TrimSpace(raw.Name),
+		URI:   strings.TrimSpace(raw.URI),
+		Email: strings.TrimSpace(raw.Email),
+	}
+	if a != (Author{}) {
+		return a
+	}
+
+	text := strings.TrimSpace(raw.Text)
+	if m := rssAuthorRegex.FindStringSubmatch(text); m != nil {
+		return Author{Name: strings.TrimSpace(m[2]), Email: m[1]}
+	}
+	if strings.Contains(text, "@") && !strings.ContainsAny(text, " \t") {
+		return Author{Email: text}
+	}
+	return Author{Name: text}
+}
+
+// getAuthorDetails is every author on the item, <author>s first then dc:creators
+func getAuthorDetails(item Item) []Author {
+	var authors []Author
+	for _, raw := range item.Authors {
+		if a := raw.author(); a.display() != "" {
+			authors = append(authors, a)
+		}
+	}
+	for _, creator := range item.Creators {
+		if creator = strings.TrimSpace(creator); creator != "" {
+			authors = append(authors, Author{Name: creator})
+		}
+	}
+
+	return authors
+}
+
+func getAuthor(item Item, channelTitle string) string {
+	if authors := getAuthors(item); len(authors) > 0 {
+		return authors[0]
+	}
+	return channelTitle
+}
+
+func getAuthors(item Item) []string {
+	var authors []string
+	for _, a := range getAuthorDetails(item) {
+		authors = append(authors, a.display())
+	}
+
+	return authors
+}
+
+func firstAuthor(authors []Author) Author {
+	if len(authors) == 0 {
+		return Author{}
+	}
+	return authors[0]
+}
@@ -0,0 +1,121 @@
+package feed
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+const columnistsFeed = `<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel><title>The Daily</title>
+<item><title>Markets are up</title><link>https://example.com/1</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate><dc:creator>Jane Doe</dc:creator></item>
+<item><title>Markets are down</title><link>https://example.com/2</link><pubDate>Tue, 02 Jan 2024 10:00:00 GMT</pubDate><author>bob@example.com (Bob Roe)</author></item>
+<item><title>Weather report</title><link>https://example.com/3</link><pubDate>Wed, 03 Jan 2024 10:00:00 GMT</pubDate><dc:creator>jane doe</dc:creator></item>
+<item><title>Staff note</title><link>https://example.com/4</link><pubDate>Thu, 04 Jan 2024 10:00:00 GMT</pubDate></item>
+</channel></rss>`
+
+func TestAuthorFilter(t *testing.T) {
+	srv := feedtest.NewServer([]byte(columnistsFeed))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"no filter", nil, []string{"Markets are up", "Markets are down", "Weather report", "Staff note"}},
+		{"denylist", []Option{WithAuthorDenylist("JANE DOE")}, []string{"Markets are down", "Staff note"}},
+		{"allowlist", []Option{WithAuthorAllowlist(" bob roe ")}, []string{"Markets are down"}},
+		// an uncredited post's author is the feed's title
+		{"deny the feed itself", []Option{WithAuthorDenylist("the daily")}, []string{"Markets are up", "Markets are down", "Weather report"}},
+		{"both", []Option{WithAuthorAllowlist("Jane Doe", "Bob Roe"), WithAuthorDenylist("Bob Roe")}, []string{"Markets are up", "Weather report"}},
+	}
+	for _, tt := range tests {
+		posts, err := FetchFeed(srv.URL, tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var titles []string
+		for _, post := range posts {
+			titles = append(titles, post.Title)
+		}
+		if !reflect.DeepEqual(titles, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, titles, tt.want)
+		}
+	}
+}
+
+func TestAuthors(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    string
+		author  string
+		authors []string
+	}{
+		{"two creators", `<dc:creator>Jane Doe</dc:creator><dc:creator>Bob Roe</dc:creator>`, "Jane Doe", []string{"Jane Doe", "Bob Roe"}},
+		{"one creator", `<dc:creator>Jane Doe</dc:creator>`, "Jane Doe", []string{"Jane Doe"}},
+		{"blank creator skipped", `<dc:creator> </dc:creator><dc:creator>Bob Roe</dc:creator>`, "Bob Roe", []string{"Bob Roe"}},
+	}
+	for _, tt := range tests {
+		body := `<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel><title>The Daily</title>
+<item><title>Co-written</title><link>https://example.com/1</link>` + tt.item + `</item></channel></rss>`
+		posts, err := ParseFeed(strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if posts[0].Author != tt.author || !reflect.DeepEqual(posts[0].Authors, tt.authors) {
+			t.Errorf("%s: got %q %q, want %q %q", tt.name, posts[0].Author, posts[0].Authors, tt.author, tt.authors)
+		}
+	}
+}
+
+func TestAuthorDetail(t *testing.T) {
+	tests := []struct {
+		name   string
+		author string
+		want   Author
+		shown  string
+	}{
+		{"full atom author", `<author><name> Jane Doe </name><email>jane@example.com</email><uri>https://jane.example.com/</uri></author>`,
+			Author{Name: "Jane Doe", URI: "https://jane.example.com/", Email: "jane@example.com"}, "Jane Doe"},
+		{"name only", `<author><name>Jane Doe</name></author>`, Author{Name: "Jane Doe"}, "Jane Doe"},
+		{"email only", `<author><email>jane@example.com</email></author>`, Author{Email: "jane@example.com"}, "jane@example.com"},
+		// nobody credited leaves the detail empty, Author still falls back to the feed
+		{"no author", ``, Author{}, "The Daily"},
+	}
+	for _, tt := range tests {
+		posts, err := ParseFeed(strings.NewReader(`<feed xmlns="http://www.w3.org/2005/Atom"><title>The Daily</title>
+<entry><title>Post</title><id>urn:1</id><link href="https://example.com/1"/>` + tt.author + `</entry></feed>`))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if posts[0].AuthorDetail != tt.want || posts[0].Author != tt.shown {
+			t.Errorf("%s: got %+v & %q, want %+v & %q", tt.name, posts[0].AuthorDetail, posts[0].Author, tt.want, tt.shown)
+		}
+	}
+}
+
+func TestFeedContacts(t *testing.T) {
+	tests := []struct {
+		name                string
+		contacts            string
+		managing, webmaster Author
+	}{
+		{"both", `<managingEditor>editor@example.com (Jane Doe)</managingEditor><webMaster>ops@example.com (Ops Team)</webMaster>`,
+			Author{Name: "Jane Doe", Email: "editor@example.com"}, Author{Name: "Ops Team", Email: "ops@example.com"}},
+		{"bare email & bare name", `<managingEditor> editor@example.com </managingEditor><webMaster>The Ops Team</webMaster>`,
+			Author{Email: "editor@example.com"}, Author{Name: "The Ops Team"}},
+		{"neither", ``, Author{}, Author{}},
+	}
+	for _, tt := range tests {
+		info, _, err := ParseFeedInfo(strings.NewReader(`<rss version="2.0"><channel><title>The Daily</title>` + tt.contacts + `
+<item><title>Post</title><link>https://example.com/1</link></item></channel></rss>`))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if info.ManagingEditor != tt.managing || info.WebMaster != tt.webmaster {
+			t.Errorf("%s: got %+v & %+v, want %+v & %+v", tt.name, info.ManagingEditor, info.WebMaster, tt.managing, tt.webmaster)
+		}
+	}
+}
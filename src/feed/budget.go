@@ -0,0 +1,121 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// TotalBudget caps a whole FetchAllFeeds run rather than any one feed, for
+// places with hard limits like serverless functions. Zero means no cap.
+type TotalBudget struct {
+	// MaxTotalBytes is how many body bytes every feed together may download
+	MaxTotalBytes int64
+	// MaxTotalDuration is how long the run may take, start to finish
+	MaxTotalDuration time.Duration
+}
+
+// WithTotalBudget stops a multi-feed fetch once the budget runs out and
+// keeps what it already has. Whatever was still downloading gets cancelled
+// & counts as skipped, see FetchAllFeedsBudget for which ones those were.
+func WithTotalBudget(budget TotalBudget) Option {
+	return func(o *options) {
+		o.budget = budget
+	}
+}
+
+// BudgetResult is what FetchAllFeedsBudget got done before the budget ran out.
+type BudgetResult struct {
+	// Posts is merged, deduped & sorted like FetchAllFeeds
+	Posts []BlogPost
+	// Skipped are the feeds that were cancelled when the budget ran out,
+	// in input order. feeds that failed by themselves are just logged.
+	Skipped []string
+}
+
+func FetchAllFeedsBudget(ctx context.Context, feeds []string, opts ...Option) BudgetResult {
+	return NewFetcher(opts...).FetchAllFeedsBudget(ctx, feeds)
+}
+
+// FetchAllFeedsBudget is FetchAllFeedsContext that also says which feeds
+// WithTotalBudget cut off.
+func (f *Fetcher) FetchAllFeedsBudget(ctx context.Context, feeds []string) BudgetResult {
+	res, _ := f.fetchAll(ctx, f.requests(feeds), false)
+	capSources(res.groups, f.opts)
+	return BudgetResult{
+		Posts:   mergePosts(flatten(res.groups), f.opts),
+		Skipped: res.skipped,
+	}
+}
+
+// budget is a TotalBudget in progress, shared by every fetch in a run
+type budget struct {
+	max      int64
+	used     atomic.Int64
+	exceeded atomic.Bool
+	cancel   context.CancelFunc
+}
+
+type budgetKey struct{}
+
+// withBudget puts a run's budget on ctx. the returned context ends when
+// either half of the budget runs out.
+func withBudget(ctx context.Context, tb TotalBudget) (context.Context, context.CancelFunc) {
+	cancel := context.CancelFunc(func() {})
+	if tb.MaxTotalDuration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, tb.MaxTotalDuration)
+	}
+	if tb.MaxTotalBytes <= 0 {
+		return ctx, cancel
+	}
+
+	ctx, cancelBytes := context.WithCancel(ctx)
+	b := &budget{max: tb.MaxTotalBytes, cancel: cancelBytes}
+	return context.WithValue(ctx, budgetKey{}, b), func() {
+		cancelBytes()
+		cancel()
+	}
+}
+
+func budgetFrom(ctx context.Context) *budget {
+	b, _ := ctx.Value(budgetKey{}).(*budget)
+	return b
+}
+
+// spend counts n more bytes, calling off the whole run once there are too many
+func (b *budget) spend(n int) bool {
+	if b.used.Add(int64(n)) <= b.max {
+		return true
+	}
+	if b.exceeded.CompareAndSwap(false, true) {
+		b.cancel()
+	}
+	return false
+}
+
+// budgetReader counts every byte it reads against the run's budget
+type budgetReader struct {
+	r io.Reader
+	b *budget
+}
+
+func (br budgetReader) Read(p []byte) (int, error) {
+	n, err := br.r.Read(p)
+	if !br.b.spend(n) {
+		return n, fmt.Errorf("more than %d bytes across all feeds: %w", br.b.max, ErrBudgetExceeded)
+	}
+	return n, err
+}
+
+// overBudget says whether a feed failed because the run's budget ran out,
+// as opposed to failing on its own (or the caller giving up). budgeted is
+// the context withBudget handed back, which only ends early for the budget.
+func overBudget(parent, budgeted context.Context, err error) bool {
+	if errors.Is(err, ErrBudgetExceeded) {
+		return true
+	}
+	return parent.Err() == nil && budgeted.Err() != nil
+}
@@ -0,0 +1,58 @@
+package feed
+
+// some feeds "bump" a post by re-publishing the same item over and over with
+// a fresh date. bumpCollapser sits in front of the parse callback and folds
+// each run of back-to-back copies into the newest one.
+type bumpCollapser struct {
+	fn      func(BlogPost) error
+	pending *BlogPost
+}
+
+func (c *bumpCollapser) add(post BlogPost) error {
+	if c.pending != nil && sameItem(*c.pending, post) {
+		if newer(post, *c.pending) {
+			c.pending = &post
+		}
+		return nil
+	}
+
+	if err := c.flush(); err != nil {
+		return err
+	}
+	c.pending = &post
+	return nil
+}
+
+func (c *bumpCollapser) flush() error {
+	if c.pending == nil {
+		return nil
+	}
+	post := *c.pending
+	c.pending = nil
+	return c.fn(post)
+}
+
+// the guid is the item's identity, but bumpers sometimes mint a new one each
+// time, so the same link counts too
+func sameItem(a, b BlogPost) bool {
+	if a.GUID != "" && a.GUID == b.GUID {
+		return true
+	}
+	return a.Link != "" && a.Link == b.Link
+}
+
+func newer(a, b BlogPost) bool {
+	if !a.UpdatedAt.Equal(b.UpdatedAt) {
+		return a.UpdatedAt.After(b.UpdatedAt)
+	}
+	return a.PublishedAt.After(b.PublishedAt)
+}
+
+// WithCollapseBumps folds consecutive copies of the same item (same GUID or
+// link) within a feed into the most recently dated one. Copies that aren't
+// next to each other are left for dedup to deal with.
+func WithCollapseBumps() Option {
+	return func(o *options) {
+		o.collapseBumps = true
+	}
+}
@@ -0,0 +1,53 @@
+package feed
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+func TestCollapseBumps(t *testing.T) {
+	body, err := os.ReadFile("testdata/bumped.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := feedtest.NewServer(body)
+	defer srv.Close()
+
+	type kept struct {
+		title string
+		day   int
+	}
+	tests := []struct {
+		name string
+		opts []Option
+		want []kept
+	}{
+		{"off", nil, []kept{{"Big spring sale", 6}, {"Big spring sale", 5}, {"Big spring sale", 4}, {"New arrivals", 3}, {"Big spring sale", 2}}},
+		// the three in a row become the newest, the one later on isn't next
+		// to them so it stays
+		{"collapsed", []Option{WithCollapseBumps()}, []kept{{"Big spring sale", 6}, {"New arrivals", 3}, {"Big spring sale", 2}}},
+	}
+	for _, tt := range tests {
+		posts, err := FetchFeed(srv.URL, tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []kept
+		for _, post := range posts {
+			got = append(got, kept{post.Title, post.PublishedAt.Day()})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// & merging takes care of the straggler
+	merged := FetchAllFeeds([]string{srv.URL}, WithCollapseBumps())
+	if len(merged) != 2 || !merged[0].PublishedAt.Equal(time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("merged: got %d posts, newest %v", len(merged), merged[0].PublishedAt)
+	}
+}
@@ -0,0 +1,227 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// CacheEntry is what a Fetcher remembers about a feed between fetches: the
+// validators for conditional requests and the body they vouch for.
+type CacheEntry struct {
+	ETag         string
+	LastModified string
+	Body         []byte
+}
+
+func (e CacheEntry) hasValidators() bool {
+	return e.ETag != "" || e.LastModified != ""
+}
+
+// Store is where a Fetcher keeps its CacheEntry per feed URL. The default
+// lives in memory, FileStore survives restarts. Implementations have to be
+// safe for concurrent use.
+type Store interface {
+	Get(url string) (CacheEntry, bool, error)
+	Set(url string, entry CacheEntry) error
+	Delete(url string) error
+}
+
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{entries: make(map[string]CacheEntry)}
+}
+
+func (c *memoryCache) Get(url string) (CacheEntry, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[url]
+	return entry, ok, nil
+}
+
+func (c *memoryCache) Set(url string, entry CacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[url] = entry
+	return nil
+}
+
+func (c *memoryCache) Delete(url string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, url)
+	return nil
+}
+
+// a nil store means caching is off. a broken store shouldn't break fetching
+// either, so its errors just get logged & treated as a miss.
+func (f *Fetcher) cacheGet(url string) (CacheEntry, bool) {
+	if f.cache == nil {
+		return CacheEntry{}, false
+	}
+
+	entry, ok, err := f.cache.Get(url)
+	if err != nil {
+		log.Printf("warn: reading cache for %s: %v", url, err)
+		return CacheEntry{}, false
+	}
+	return entry, ok
+}
+
+func (f *Fetcher) cacheSet(url string, entry CacheEntry) {
+	if f.cache == nil {
+		return
+	}
+
+	if err := f.cache.Set(url, entry); err != nil {
+		log.Printf("warn: writing cache for %s: %v", url, err)
+	}
+}
+
+// parsedCache remembers the last parse of each feed against a hash of the
+// body it came from, so an unchanged feed skips parsing altogether
+type parsedCache struct {
+	mu      sync.Mutex
+	entries map[string]parsedEntry
+}
+
+type parsedEntry struct {
+	sum   [sha256.Size]byte
+	info  FeedInfo
+	posts []BlogPost
+}
+
+func newParsedCache() *parsedCache {
+	return &parsedCache{entries: make(map[string]parsedEntry)}
+}
+
+func (c *parsedCache) get(url string, sum [sha256.Size]byte) (FeedInfo, []BlogPost, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[url]
+	if !ok || entry.sum != sum {
+		return FeedInfo{}, nil, false
+	}
+	// callers are free to re-slice & sort what they get, so hand out a copy
+	return entry.info, append([]BlogPost(nil), entry.posts...), true
+}
+
+func (c *parsedCache) set(url string, sum [sha256.Size]byte, info FeedInfo, posts []BlogPost) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[url] = parsedEntry{sum: sum, info: info, posts: append([]BlogPost(nil), posts...)}
+}
+
+// InvalidateAndFetch is a "refresh now" for a single feed: it skips the HEAD
+// probe and conditional headers, downloads the feed in full and caches the
+// new body & validators. If the fetch fails, the old cache entry stays put.
+func (f *Fetcher) InvalidateAndFetch(ctx context.Context, url string) ([]BlogPost, error) {
+	o := *f.opts
+	o.forceRefresh = true
+
+	_, posts, err := f.fetchFeed(ctx, url, &o)
+	return posts, err
+}
+
+// Reparse parses the cached body for url again with opts layered over the
+// Fetcher's options, no network involved, e.g. after changing the summary
+// length. Fails with ErrNotCached when there's no body to reparse.
+func (f *Fetcher) Reparse(url string, opts ...Option) ([]BlogPost, error) {
+	entry, ok := f.cacheGet(url)
+	if !ok || entry.Body == nil {
+		return nil, fmt.Errorf("reparsing %s: %w", url, ErrNotCached)
+	}
+
+	_, posts, err := parseFeedPosts(bytes.NewReader(entry.Body), f.optionsFor(opts).fetchedFrom(url))
+	if err != nil {
+		return nil, withURL(err, url)
+	}
+
+	tagSource(posts, url)
+	return posts, nil
+}
+
+// unchanged asks the server, via a conditional HEAD, whether the cached body
+// is still current. any doubt at all (405, network trouble, no validators to
+// compare) means false and the caller just does a normal GET.
+func (f *Fetcher) unchanged(ctx context.Context, url string, entry CacheEntry, o *options) bool {
+	if !entry.hasValidators() {
+		return false
+	}
+
+	req, err := f.newRequest(ctx, http.MethodHead, url, entry, o)
+	if err != nil {
+		return false
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return true
+	case resp.StatusCode != http.StatusOK:
+		return false
+	}
+
+	// plenty of servers ignore conditional HEAD but still send validators
+	if entry.ETag != "" {
+		return resp.Header.Get("ETag") == entry.ETag
+	}
+	return resp.Header.Get("Last-Modified") == entry.LastModified
+}
+
+// WithCache makes the Fetcher remember each feed's ETag, Last-Modified and
+// body, and send conditional requests so a 304 just reuses what it has.
+func WithCache() Option {
+	return func(o *options) {
+		o.cache = true
+	}
+}
+
+// WithStore is WithCache backed by s instead of memory, e.g. a FileStore so
+// a cron job only downloads what changed since its last run.
+func WithStore(s Store) Option {
+	return func(o *options) {
+		o.cache = true
+		o.store = s
+	}
+}
+
+// WithParsedCache keeps each feed's parsed posts alongside its cached body.
+// When a fetch comes back byte-for-byte identical to the last one, those
+// posts are returned without parsing again. Anything the parse worked out
+// from the clock (undated posts, relative dates, WithFuturePolicy) stays as
+// it was the first time. Implies WithCache.
+func WithParsedCache() Option {
+	return func(o *options) {
+		o.cache = true
+		o.parsedCache = true
+	}
+}
+
+// WithHeadProbe is a polling mode for big feeds that rarely change: before
+// downloading, a conditional HEAD checks whether the cached copy is still
+// good. Servers that refuse HEAD (405) or don't answer it usefully get a
+// regular conditional GET instead. Implies WithCache.
+func WithHeadProbe() Option {
+	return func(o *options) {
+		o.headProbe = true
+	}
+}
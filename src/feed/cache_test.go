@@ -0,0 +1,194 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+// largeFeed is n posts with a few KB of content each
+func largeFeed(n int) []byte {
+	var b strings.Builder
+	b.WriteString(`<rss version="2.0"><channel><title>Big</title>`)
+	paragraph := strings.Repeat("<p>lots &amp; lots of words about nothing much</p>", 50)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<item><title>Post %d</title><link>https://example.com/%d</link><guid>https://example.com/%d</guid>`, i, i, i)
+		fmt.Fprintf(&b, `<pubDate>Mon, 01 Jan 2024 10:%02d:00 GMT</pubDate><description><![CDATA[%s]]></description></item>`, i%60, paragraph)
+	}
+	b.WriteString(`</channel></rss>`)
+	return []byte(b.String())
+}
+
+// BenchmarkUnchangedLargeFeed polls a big feed that never changes & reports
+// how many body bytes each poll cost. half the servers out there ignore
+// If-None-Match on GET while still sending an ETag, which is where the HEAD
+// probe earns its keep.
+func BenchmarkUnchangedLargeFeed(b *testing.B) {
+	body := largeFeed(200)
+	const etag = `"big-v1"`
+
+	servers := []struct {
+		name string
+		// whether GET honors If-None-Match
+		conditional bool
+	}{
+		{"conditional server", true},
+		{"ignores conditional GET", false},
+	}
+	modes := []struct {
+		name string
+		opts []Option
+	}{
+		{"no cache", nil},
+		{"conditional GET", []Option{WithCache()}},
+		{"head probe", []Option{WithHeadProbe()}},
+	}
+
+	for _, s := range servers {
+		var sent atomic.Int64
+		conditional := s.conditional
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Content-Type", "application/rss+xml")
+			if r.Header.Get("If-None-Match") == etag && (conditional || r.Method == http.MethodHead) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			if r.Method == http.MethodHead {
+				return
+			}
+			n, _ := w.Write(body)
+			sent.Add(int64(n))
+		}))
+
+		for _, m := range modes {
+			b.Run(s.name+"/"+m.name, func(b *testing.B) {
+				f := NewFetcher(m.opts...)
+				if _, err := f.FetchFeed(srv.URL); err != nil {
+					b.Fatal(err)
+				}
+				sent.Store(0)
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := f.FetchFeed(srv.URL); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(sent.Load())/float64(b.N), "body-bytes/op")
+			})
+		}
+		srv.Close()
+	}
+}
+
+// BenchmarkParsedCache polls a feed that's byte-for-byte the same every
+// time, from a server that never sends validators: cold parses every body,
+// warm only hashes it & hands back the posts from last time
+func BenchmarkParsedCache(b *testing.B) {
+	srv := feedtest.NewServer(largeFeed(200))
+	defer srv.Close()
+
+	b.Run("cold", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := NewFetcher(WithParsedCache()).FetchFeed(srv.URL); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("warm", func(b *testing.B) {
+		f := NewFetcher(WithParsedCache())
+		want, err := f.FetchFeed(srv.URL)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			posts, err := f.FetchFeed(srv.URL)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(posts) != len(want) || posts[0].Summary != want[0].Summary {
+				b.Fatal("warm fetch came back different")
+			}
+		}
+	})
+}
+
+func TestInvalidateAndFetch(t *testing.T) {
+	// a stale CDN: anything conditional gets a 304, even though the feed
+	// itself has moved on to v2
+	var version atomic.Int32
+	version.Store(1)
+	var broken atomic.Bool
+	var lastINM atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if broken.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		inm := r.Header.Get("If-None-Match")
+		lastINM.Store(inm)
+		if inm != "" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		v := version.Load()
+		w.Header().Set("ETag", fmt.Sprintf(`"v%d"`, v))
+		if r.Method != http.MethodHead {
+			fmt.Fprint(w, postsFeed(fmt.Sprintf("v%d", v), int(v)))
+		}
+	}))
+	defer srv.Close()
+
+	for _, mode := range []struct {
+		name string
+		opts []Option
+	}{
+		{"conditional GET", nil},
+		{"head probe", []Option{WithHeadProbe()}},
+	} {
+		version.Store(1)
+		broken.Store(false)
+		store := newMemoryCache()
+		f := NewFetcher(append([]Option{WithStore(store)}, mode.opts...)...)
+
+		if posts, err := f.FetchFeed(srv.URL); err != nil || len(posts) != 1 {
+			t.Fatalf("%s: first fetch got %d posts, %v", mode.name, len(posts), err)
+		}
+		version.Store(2)
+		if posts, _ := f.FetchFeed(srv.URL); len(posts) != 1 {
+			t.Fatalf("%s: the stale 304 should still give the old post, got %d", mode.name, len(posts))
+		}
+
+		posts, err := f.InvalidateAndFetch(context.Background(), srv.URL)
+		if err != nil || len(posts) != 2 {
+			t.Fatalf("%s: refresh got %d posts, %v", mode.name, len(posts), err)
+		}
+		entry, ok, _ := store.Get(srv.URL)
+		if !ok || entry.ETag != `"v2"` || !strings.Contains(string(entry.Body), "v2 post 2") {
+			t.Errorf("%s: store has ETag %q after the refresh, want the fresh one", mode.name, entry.ETag)
+		}
+
+		// & the next ordinary fetch goes on from the fresh validators
+		if posts, _ := f.FetchFeed(srv.URL); len(posts) != 2 || lastINM.Load() != `"v2"` {
+			t.Errorf("%s: next fetch got %d posts, sent If-None-Match %v", mode.name, len(posts), lastINM.Load())
+		}
+
+		// a refresh that fails leaves what's cached alone
+		broken.Store(true)
+		if _, err := f.InvalidateAndFetch(context.Background(), srv.URL); err == nil {
+			t.Errorf("%s: refresh against a broken server didn't fail", mode.name)
+		}
+		if after, _, _ := store.Get(srv.URL); after.ETag != `"v2"` || string(after.Body) != string(entry.Body) {
+			t.Errorf("%s: failed refresh changed the entry to ETag %q", mode.name, after.ETag)
+		}
+	}
+}
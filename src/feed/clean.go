@@ -0,0 +1,226 @@
+package feed
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// We're rendering to markdown so to preserve formatting we need to strip out any markdown characters
+func stripMarkdown(input string) string {
+	invalidChars := []string{"*", "_", "#", "`", ">", "<", "[", "]", "(", ")", "!", "~", "|", "{", "}", "+"}
+	for _, char := range invalidChars {
+		input = strings.ReplaceAll(input, char, "")
+	}
+
+	return input
+}
+
+var (
+	tagRegex    = regexp.MustCompile("<[^>]*>")
+	wsRegex     = regexp.MustCompile(`\s+`)
+	entityRegex = regexp.MustCompile(`(?i)&(?:[a-z][a-z0-9]*|#[0-9]+|#x[0-9a-f]+);`)
+
+	// only used with WithParagraphs
+	paragraphRegex = regexp.MustCompile(`(?i)</(?:p|div|blockquote|h[1-6]|ul|ol|pre)\s*>`)
+	lineBreakRegex = regexp.MustCompile(`(?i)<br\s*/?>|</li\s*>`)
+	spacesRegex    = regexp.MustCompile(`[^\S\n]+`)
+	blankRunRegex  = regexp.MustCompile(` ?\n ?`)
+	newlinesRegex  = regexp.MustCompile(`\n{3,}`)
+
+	// only used with WithMarkdownSummaries
+	mdTrailingSpace = regexp.MustCompile(`[ \t]+\n`)
+	mdLeadingSpace  = regexp.MustCompile(`\n[ \t]+`)
+)
+
+// a triple-encoded feed is already one too many, nobody needs more passes than this
+const maxUnescapePasses = 3
+
+// unescapeAgain handles feeds that encode twice ("&amp;amp;", "&amp;lt;p&amp;gt;").
+// we only go another round while something that looks like an entity is
+// still sitting there, and decoded markup gets stripped again each time.
+func unescapeAgain(input string) string {
+	for i := 0; i < maxUnescapePasses && entityRegex.MatchString(input); i++ {
+		next := tagRegex.ReplaceAllString(html.UnescapeString(input), "")
+		if next == input {
+			break
+		}
+		input = next
+	}
+
+	return input
+}
+
+// stripControl turns \r\n & lone \r into \n and drops every other control
+// character except tabs. they sneak in raw or as entities (&#11;) and come
+// out the other end as garbage in plain text & markdown.
+func stripControl(input string) string {
+	input = strings.ReplaceAll(input, "\r\n", "\n")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\r':
+			return '\n'
+		case r == '\n' || r == '\t':
+			return r
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, input)
+}
+
+var charRefRegex = regexp.MustCompile(`&#(?:[xX]([0-9a-fA-F]{1,8})|([0-9]{1,10}));`)
+
+// dropControlRefs removes &#11; & co before the markup gets anywhere near
+// an xml.Decoder, which refuses references to control characters outright
+// (& html.UnescapeString turns &#0; into a replacement character)
+func dropControlRefs(input string) string {
+	if !strings.Contains(input, "&#") {
+		return input
+	}
+	return charRefRegex.ReplaceAllStringFunc(input, func(ref string) string {
+		m := charRefRegex.FindStringSubmatch(ref)
+		n, err := strconv.ParseUint(m[2], 10, 32)
+		if m[1] != "" {
+			n, err = strconv.ParseUint(m[1], 16, 32)
+		}
+		if err != nil {
+			return ref
+		}
+		if r := rune(n); r != '\t' && r != '\n' && r != '\r' && unicode.IsControl(r) {
+			return ""
+		}
+		return ref
+	})
+}
+
+// asciiPunctuation swaps typography for the plain ASCII it stands in for,
+// for terminals & tools that choke on anything else
+var asciiPunctuation = strings.NewReplacer(
+	"\u2018", "'", "\u2019", "'", "\u201a", "'", "\u2032", "'",
+	"\u201c", `"`, "\u201d", `"`, "\u201e", `"`, "\u2033", `"`,
+	"\u2010", "-", "\u2011", "-", "\u2013", "-", "\u2014", "-", "\u2015", "-", "\u2212", "-",
+	"\u2026", "...",
+	// non-breaking & other odd-width spaces, which \s doesn't match
+	"\u00a0", " ", "\u2007", " ", "\u2009", " ", "\u200a", " ", "\u202f", " ",
+	"\u200b", "",
+)
+
+func (o *options) typography(input string) string {
+	if !o.asciiPunctuation {
+		return input
+	}
+	return asciiPunctuation.Replace(input)
+}
+
+// cleanHTML turns a chunk of feed HTML into one line of plain text (or a few
+// paragraphs of it with WithParagraphs).
+// truncating is left to the caller since summary & excerpt want different lengths.
+func cleanHTML(input string, o *options) string {
+	if o.paragraphs {
+		return cleanParagraphs(input, o)
+	}
+
+	// first, remove HTML tags
+	cleaned := tagRegex.ReplaceAllString(dropControlRefs(input), "")
+
+	// & convert HTML entities
+	cleaned = html.UnescapeString(cleaned)
+	if o.repeatedUnescape {
+		cleaned = unescapeAgain(cleaned)
+	}
+	cleaned = o.typography(stripControl(cleaned))
+
+	// & normalize whitespace
+	cleaned = wsRegex.ReplaceAllString(cleaned, " ")
+
+	// & chop off "Continue reading →" and friends before they eat into the length limit
+	if o.boilerplate != nil {
+		cleaned = trimBoilerplate(cleaned, o.boilerplate)
+	}
+
+	return strings.TrimSpace(cleaned)
+}
+
+// cleanParagraphs is cleanHTML that turns block tags into line breaks
+// before they get stripped: a blank line between paragraphs, a single
+// newline for <br> & list items
+func cleanParagraphs(input string, o *options) string {
+	// newlines in the source are just whitespace as far as HTML is concerned
+	cleaned := wsRegex.ReplaceAllString(dropControlRefs(input), " ")
+	cleaned = paragraphRegex.ReplaceAllString(cleaned, "\n\n")
+	cleaned = lineBreakRegex.ReplaceAllString(cleaned, "\n")
+
+	cleaned = tagRegex.ReplaceAllString(cleaned, "")
+	cleaned = html.UnescapeString(cleaned)
+	if o.repeatedUnescape {
+		cleaned = unescapeAgain(cleaned)
+	}
+	cleaned = o.typography(stripControl(cleaned))
+
+	cleaned = spacesRegex.ReplaceAllString(cleaned, " ")
+	cleaned = blankRunRegex.ReplaceAllString(cleaned, "\n")
+	cleaned = newlinesRegex.ReplaceAllString(cleaned, "\n\n")
+
+	if o.boilerplate != nil {
+		cleaned = trimBoilerplate(cleaned, o.boilerplate)
+	}
+
+	return strings.TrimSpace(cleaned)
+}
+
+// counts in runes so we never slice a multi-byte character in half, and cuts
+// on a word boundary when there's one reasonably close. a length of 0 or
+// less means no limit at all.
+func truncate(input string, maxLength int, o *options) string {
+	runes := []rune(input)
+	if maxLength <= 0 || len(runes) <= maxLength {
+		return input
+	}
+
+	// with a strict cap the ellipsis has to fit inside maxLength too
+	limit := maxLength
+	if o.strictTruncation {
+		limit -= utf8.RuneCountInString(o.ellipsis)
+		if limit < 0 {
+			limit = 0
+		}
+	}
+
+	// back up to the last word break, unless that throws away more than half
+	// or the cut already landed on one
+	cut := string(runes[:limit])
+	if i := strings.LastIndexByte(cut, ' '); i > len(cut)/2 && runes[limit] != ' ' {
+		cut = cut[:i]
+	}
+
+	cut = strings.TrimRight(cut, " .,;:")
+	return cut + o.ellipsis
+}
+
+// the usual WordPress / blog-engine tails that survive tag stripping
+var defaultBoilerplate = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\s*(?:…|\.\.\.)\s*continue reading\b.{0,150}$`),
+	regexp.MustCompile(`(?i)\s*continue reading\b.{0,150}?(?:→|»)$`),
+	regexp.MustCompile(`(?i)\s*(?:…|\.\.\.)?\s*read more\s*(?:→|»|\.\.\.|…)?$`),
+	regexp.MustCompile(`(?i)\s*the post .{1,200} appeared first on .{1,200}$`),
+	regexp.MustCompile(`\s*\[(?:…|\.\.\.)\]$`),
+}
+
+func trimBoilerplate(input string, patterns []*regexp.Regexp) string {
+	// tails like to stack ("[…] The post X appeared first on Y."), so keep going until nothing matches
+	for {
+		trimmed := input
+		for _, pattern := range patterns {
+			trimmed = pattern.ReplaceAllString(trimmed, "")
+		}
+		trimmed = strings.TrimSpace(trimmed)
+		if trimmed == input {
+			return trimmed
+		}
+		input = trimmed
+	}
+}
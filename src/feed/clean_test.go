@@ -0,0 +1,259 @@
+package feed
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+func TestBoilerplateTrimming(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		in   string
+		want string
+	}{
+		{
+			"continue reading arrow", nil,
+			`<p>We shipped the new release today.</p> <p><a href="https://example.com/1" class="more-link">Continue reading <span class="screen-reader-text">Release notes</span> <span class="meta-nav">&rarr;</span></a></p>`,
+			"We shipped the new release today.",
+		},
+		{
+			"ellipsis & continue reading", nil,
+			`<p>The garden is finally blooming &hellip; <a href="https://example.com/2">Continue reading &#8220;Spring&#8221;</a></p>`,
+			"The garden is finally blooming",
+		},
+		{
+			"read more", nil,
+			`<p>Short update on the build server. <a href="https://example.com/3">Read more &raquo;</a></p>`,
+			"Short update on the build server.",
+		},
+		{
+			"stacked tails", nil,
+			`<p>A long post about caching [&#8230;]</p><p>The post <a href="https://example.com/4">Caching</a> appeared first on <a href="https://example.com">Example Blog</a>.</p>`,
+			"A long post about caching",
+		},
+		{
+			"only at the end", nil,
+			`<p>Read more about it below. It's good.</p>`,
+			"Read more about it below. It's good.",
+		},
+		{
+			"custom pattern", []Option{WithBoilerplateTrimming(regexp.MustCompile(`\s*-- sent from my phone$`))},
+			`<p>Quick note -- sent from my phone</p>`,
+			"Quick note",
+		},
+		{
+			"off by default", []Option{},
+			`<p>Short update. <a href="https://example.com/3">Read more &raquo;</a></p>`,
+			"Short update. Read more »",
+		},
+	}
+	for _, tt := range tests {
+		opts := tt.opts
+		if opts == nil {
+			opts = []Option{WithBoilerplateTrimming()}
+		}
+		if got := cleanHTML(tt.in, newOptions(opts)); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateEllipsis(t *testing.T) {
+	const text = "The quick brown fox jumps over the lazy dog"
+	tests := []struct {
+		name string
+		in   string
+		opts []Option
+		max  int
+		want string
+	}{
+		{"default marker", "", nil, 20, "The quick brown fox..."},
+		{"unicode marker", "", []Option{WithEllipsis("…")}, 20, "The quick brown fox…"},
+		{"localized marker", "", []Option{WithEllipsis(" (suite)")}, 20, "The quick brown fox (suite)"},
+		{"no marker", "", []Option{WithEllipsis("")}, 20, "The quick brown fox"},
+		{"fits", "", nil, len(text), text},
+		{"strict default", "", []Option{WithStrictTruncation()}, 20, "The quick brown..."},
+		{"strict unicode", "", []Option{WithStrictTruncation(), WithEllipsis("…")}, 20, "The quick brown fox…"},
+		{"strict marker longer than the limit", "", []Option{WithStrictTruncation(), WithEllipsis(" [more]")}, 3, " [more]"},
+		{"multi-byte", "éééééééééé", []Option{WithEllipsis("…")}, 5, "ééééé…"},
+		{"no limit", "", nil, 0, text},
+		{"negative limit", "", []Option{WithStrictTruncation()}, -1, text},
+	}
+	for _, tt := range tests {
+		in := tt.in
+		if in == "" {
+			in = text
+		}
+		got := truncate(in, tt.max, newOptions(tt.opts))
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+		if strict := newOptions(tt.opts).strictTruncation; strict && tt.max >= 4 && utf8.RuneCountInString(got) > tt.max {
+			t.Errorf("%s: %q is over the %d cap", tt.name, got, tt.max)
+		}
+	}
+}
+
+func TestNegativeLengths(t *testing.T) {
+	long := strings.Repeat("words and more words ", 20)
+	body := `<rss version="2.0"><channel><title>Blog</title><item><title>Post</title><link>https://example.com/1</link>
+<pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate><description>` + long + `</description></item></channel></rss>`
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		summary bool
+		excerpt bool
+	}{
+		{"summary", []Option{WithSummaryLength(-1)}, true, false},
+		{"excerpt", []Option{WithExcerptLength(-5)}, false, true},
+		{"both", []Option{WithSummaryLength(-1), WithExcerptLength(-1)}, true, true},
+		{"markdown", []Option{WithMarkdownSummaries(), WithSummaryLength(-1), WithExcerptLength(0)}, true, true},
+		{"html", []Option{WithAllowedTags("p"), WithSummaryLength(-1)}, true, false},
+	}
+	for _, tt := range tests {
+		posts, err := ParseFeed(strings.NewReader(body), tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		whole := strings.TrimSpace(long)
+		if got := posts[0].Summary == whole; got != tt.summary {
+			t.Errorf("%s: Summary %q", tt.name, posts[0].Summary)
+		}
+		if got := posts[0].Excerpt == whole; got != tt.excerpt {
+			t.Errorf("%s: Excerpt %q", tt.name, posts[0].Excerpt)
+		}
+		if posts[0].SummaryHTML != "" && posts[0].SummaryHTML != whole {
+			t.Errorf("%s: SummaryHTML %q", tt.name, posts[0].SummaryHTML)
+		}
+	}
+}
+
+func TestRepeatedUnescape(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		once        string
+		repeated    string
+	}{
+		// single-encoded has nothing left to decode after the first pass
+		{"single", `Tom &amp; Jerry say &quot;hi&quot; &amp;c.`, `Tom & Jerry say "hi" &c.`, `Tom & Jerry say "hi" &c.`},
+		{"single, literal entity text", `Write &amp;amp; to get an ampersand`, "Write &amp; to get an ampersand", "Write & to get an ampersand"},
+		{"double", `Tom &amp;amp; Jerry at the Caf&amp;eacute;`, "Tom &amp; Jerry at the Caf&eacute;", "Tom & Jerry at the Café"},
+		{"double markup", `&amp;lt;b&amp;gt;Bold&amp;lt;/b&amp;gt; news`, "&lt;b&gt;Bold&lt;/b&gt; news", "Bold news"},
+		{"triple", `Fish &amp;amp;amp; chips`, "Fish &amp;amp; chips", "Fish & chips"},
+		{"plain", `Nothing to see here`, "Nothing to see here", "Nothing to see here"},
+	}
+	for _, tt := range tests {
+		item := `<item><title>Post</title><link>https://example.com/1</link><description><![CDATA[` + tt.description + `]]></description></item>`
+		if got := parseItemXML(t, item).Summary; got != tt.once {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.once)
+		}
+		if got := parseItemXML(t, item, WithRepeatedUnescape()).Summary; got != tt.repeated {
+			t.Errorf("%s, repeated: got %q, want %q", tt.name, got, tt.repeated)
+		}
+	}
+}
+
+func TestParagraphs(t *testing.T) {
+	const description = `<![CDATA[<p>First paragraph,
+  wrapped over two lines.</p>
+
+<p>Second one.<br>With a line break.</p><div>A div counts too</div>
+<ul><li>one</li><li>two</li></ul>]]>`
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"one line by default", nil, "First paragraph, wrapped over two lines. Second one.With a line break.A div counts too onetwo"},
+		{"paragraphs", []Option{WithParagraphs()}, "First paragraph, wrapped over two lines.\n\nSecond one.\nWith a line break.\n\nA div counts too\n\none\ntwo"},
+	}
+	for _, tt := range tests {
+		opts := append([]Option{WithSummaryLength(500)}, tt.opts...)
+		post := parseItemXML(t, `<item><title>Post</title><link>https://example.com/1</link><description>`+description+`</description></item>`, opts...)
+		if post.Summary != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, post.Summary, tt.want)
+		}
+	}
+}
+
+func TestControlCharacters(t *testing.T) {
+	body, err := os.ReadFile("testdata/control_bytes.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		summary string
+	}{
+		{"one line", nil, "Line one line two with an escape 31mcode0m Formfeed and a nul"},
+		{"paragraphs", []Option{WithParagraphs()}, "Line one line two with an escape 31mcode0m\n\nFormfeed and a nul"},
+		{"markdown", []Option{WithMarkdownSummaries()}, "Line one line two with an escape \\[31mcode\\[0m\n\nFormfeed and a nul"},
+	}
+	for _, tt := range tests {
+		for _, lenient := range []bool{false, true} {
+			opts := append([]Option{WithSummaryLength(500)}, tt.opts...)
+			if lenient {
+				opts = append(opts, WithLenient())
+			}
+			posts, err := ParseFeed(bytes.NewReader(body), opts...)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			post := posts[0]
+			if post.Summary != tt.summary {
+				t.Errorf("%s (lenient %v): got %q, want %q", tt.name, lenient, post.Summary, tt.summary)
+			}
+			if post.Title != "Bell & verticaltab" || post.SourceTitle != "Control Freak" {
+				t.Errorf("%s (lenient %v): title %q from %q", tt.name, lenient, post.Title, post.SourceTitle)
+			}
+		}
+	}
+
+	posts, err := ParseFeed(bytes.NewReader(body), WithAllowedTags("p"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<p>Line one line two with an escape [31mcode[0m</p> <p>Formfeed and a nul</p>"; posts[0].SummaryHTML != want {
+		t.Errorf("SummaryHTML %q, want %q", posts[0].SummaryHTML, want)
+	}
+}
+
+func TestASCIIPunctuation(t *testing.T) {
+	// typography as it really shows up: raw UTF-8, XML character references
+	// & HTML entities inside escaped markup
+	const item = `<item><title>“Smart” quotes — it&#8217;s here…</title><link>https://example.com/post</link>
+<description>&lt;p&gt;Don&amp;rsquo;t &amp;ldquo;panic&amp;rdquo;&amp;nbsp;&amp;ndash; 10&#160;km &amp;mdash; &#8216;fine&#8217;&amp;hellip;&lt;/p&gt;</description></item>`
+
+	tests := []struct {
+		name           string
+		opts           []Option
+		title, summary string
+	}{
+		{"kept by default", nil, "“Smart” quotes — it’s here…", "Don’t “panic”\u00a0– 10\u00a0km — ‘fine’…"},
+		{"ascii", []Option{WithASCIIPunctuation()}, `"Smart" quotes - it's here...`, `Don't "panic" - 10 km - 'fine'...`},
+		{"ascii paragraphs", []Option{WithASCIIPunctuation(), WithParagraphs()}, `"Smart" quotes - it's here...`, `Don't "panic" - 10 km - 'fine'...`},
+		{"ascii markdown", []Option{WithASCIIPunctuation(), WithMarkdownSummaries()}, `"Smart" quotes - it's here...`, `Don't "panic" - 10 km - 'fine'...`},
+	}
+	for _, tt := range tests {
+		post := parseItemXML(t, item, tt.opts...)
+		if post.Title != tt.title {
+			t.Errorf("%s: title %q, want %q", tt.name, post.Title, tt.title)
+		}
+		if post.Summary != tt.summary {
+			t.Errorf("%s: summary %q, want %q", tt.name, post.Summary, tt.summary)
+		}
+		if tt.opts != nil && strings.ContainsFunc(post.Title+post.Summary, func(r rune) bool { return r > unicode.MaxASCII }) {
+			t.Errorf("%s: still not ascii: %q / %q", tt.name, post.Title, post.Summary)
+		}
+	}
+}
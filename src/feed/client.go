@@ -0,0 +1,160 @@
+package feed
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TransportConfig is the connection-pool tuning applied to our transport.
+type TransportConfig struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	ForceAttemptHTTP2   bool
+}
+
+// DefaultTransportConfig is tuned for polling lots of hosts at once, with a
+// few of them (github.io, substack...) hosting many feeds each. Go's own
+// default of 2 idle conns per host is a bit stingy for that.
+var DefaultTransportConfig = TransportConfig{
+	MaxIdleConns:        200,
+	MaxIdleConnsPerHost: 8,
+	IdleConnTimeout:     90 * time.Second,
+	ForceAttemptHTTP2:   true,
+}
+
+func (cfg TransportConfig) apply(t *http.Transport) {
+	t.MaxIdleConns = cfg.MaxIdleConns
+	t.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	t.IdleConnTimeout = cfg.IdleConnTimeout
+	t.ForceAttemptHTTP2 = cfg.ForceAttemptHTTP2
+}
+
+// we start from a clone of http.DefaultTransport, which already honors
+// HTTP_PROXY/HTTPS_PROXY/NO_PROXY. a client passed in with WithHTTPClient
+// is left exactly as-is unless a proxy or transport config is asked for,
+// and even then we clone instead of mutating the caller's transport.
+func newHTTPClient(o *options) *http.Client {
+	client := o.client
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	tune := o.client == nil || o.transport != nil
+	if !tune && o.proxy == nil {
+		return client
+	}
+
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	transport, ok := base.(*http.Transport)
+	if !ok {
+		log.Printf("warn: can't configure custom %T transport, leaving it alone", base)
+		return client
+	}
+
+	transport = transport.Clone()
+	if tune {
+		cfg := DefaultTransportConfig
+		if o.transport != nil {
+			cfg = *o.transport
+		}
+		cfg.apply(transport)
+	}
+	if o.proxy != nil {
+		transport.Proxy = http.ProxyURL(o.proxy)
+	}
+
+	configured := *client
+	configured.Transport = transport
+	return &configured
+}
+
+// WithHTTPClient swaps in the client used for every request. A client whose
+// Transport is nil still picks up proxies from the environment.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithProxy sends every request through proxyURL instead of whatever the
+// environment says. It applies on top of WithHTTPClient too.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(o *options) {
+		o.proxy = proxyURL
+	}
+}
+
+// WithHeader adds a header to every request for the feed, e.g. an
+// Authorization for one private feed via FetchAllFeedsWith. Repeat it to add
+// more. Requests harvest makes on its own to other hosts a feed points at
+// (source feeds, favicons) never get them, see sameOrigin.
+func WithHeader(key, value string) Option {
+	return func(o *options) {
+		// options get copied per feed, so never add to a shared Header
+		o.headers = o.headers.Clone()
+		if o.headers == nil {
+			o.headers = make(http.Header)
+		}
+		o.headers.Add(key, value)
+	}
+}
+
+// WithTimeout gives up on a fetch (retries included) after d. Zero (the
+// default) means only the caller's context decides.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithMaxBodyBytes refuses feeds bigger than n bytes with ErrFeedTooLarge.
+// A Content-Length over the limit fails before the body is read at all.
+// Zero (the default) means no limit.
+func WithMaxBodyBytes(n int64) Option {
+	return func(o *options) {
+		o.maxBodyBytes = n
+	}
+}
+
+// WithTransportConfig replaces DefaultTransportConfig. It also applies to a
+// client from WithHTTPClient, as long as that client uses an *http.Transport.
+func WithTransportConfig(cfg TransportConfig) Option {
+	return func(o *options) {
+		o.transport = &cfg
+	}
+}
+
+// sameOrigin is whether target is on the same scheme, host & port as feedURL.
+// WithHeader headers are usually credentials for that one feed, so anything
+// else only gets a request without them.
+func sameOrigin(feedURL, target string) bool {
+	a, err := url.Parse(feedURL)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	return a.Host != "" && strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Host, b.Host)
+}
+
+// forTarget is o for a request harvest makes by itself to target, on the
+// way to fetching feedURL: the same options minus the headers, unless target
+// is on the feed's own origin
+func (o *options) forTarget(feedURL, target string) *options {
+	if len(o.headers) == 0 || sameOrigin(feedURL, target) {
+		return o
+	}
+	stripped := *o
+	stripped.headers = nil
+	return &stripped
+}
@@ -0,0 +1,123 @@
+package feed
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// BenchmarkManyFeedsOneHost fetches a batch of feeds that all live on one
+// host, the github.io/substack case, with harvest's transport defaults &
+// with Go's. conns/op is how many connections each batch had to open.
+func BenchmarkManyFeedsOneHost(b *testing.B) {
+	const feeds = 64
+	body := []byte(postsFeed("blog", 5))
+
+	var conns atomic.Int64
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// long enough for fetches to overlap, like a real server's latency
+		time.Sleep(time.Millisecond)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write(body)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	urls := make([]string, feeds)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("%s/feed/%d.xml", srv.URL, i)
+	}
+
+	configs := []struct {
+		name string
+		cfg  TransportConfig
+	}{
+		{"harvest", DefaultTransportConfig},
+		{"go", TransportConfig{MaxIdleConns: 100, MaxIdleConnsPerHost: http.DefaultMaxIdleConnsPerHost, IdleConnTimeout: 90 * time.Second}},
+	}
+	for _, c := range configs {
+		b.Run(c.name, func(b *testing.B) {
+			// a poller caps how many go at once, which is what keeps
+			// connections worth pooling
+			f := NewFetcher(WithTransportConfig(c.cfg), WithMaxConcurrentFetches(8))
+			// warm up the pool, a poller's first round is never the one that matters
+			f.FetchAllFeeds(urls)
+			conns.Store(0)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if posts := f.FetchAllFeeds(urls); len(posts) == 0 {
+					b.Fatal("no posts")
+				}
+			}
+			b.ReportMetric(float64(conns.Load())/float64(b.N), "conns/op")
+		})
+	}
+}
+
+func TestProxy(t *testing.T) {
+	// a plain http proxy just gets the absolute URL as the request line
+	var proxied atomic.Value
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Store(r.URL.String())
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, postsFeed("proxied", 1))
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"default client", []Option{WithProxy(proxyURL)}},
+		{"injected client", []Option{WithHTTPClient(&http.Client{Timeout: time.Minute}), WithProxy(proxyURL)}},
+		{"injected transport", []Option{WithHTTPClient(&http.Client{Transport: &http.Transport{}}), WithProxy(proxyURL)}},
+		{"tuned transport", []Option{WithTransportConfig(DefaultTransportConfig), WithProxy(proxyURL)}},
+	}
+	for _, tt := range tests {
+		proxied.Store("")
+		// .invalid never resolves, only the proxy can answer this
+		posts, err := FetchFeed("http://feeds.invalid/feed.xml", tt.opts...)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(posts) != 1 {
+			t.Errorf("%s: got %d posts, want 1", tt.name, len(posts))
+		}
+		if got := proxied.Load(); got != "http://feeds.invalid/feed.xml" {
+			t.Errorf("%s: proxy saw %q", tt.name, got)
+		}
+	}
+}
+
+func TestProxyFromEnvironmentKept(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"transport config", []Option{WithTransportConfig(TransportConfig{MaxIdleConnsPerHost: 1})}},
+	}
+	for _, tt := range tests {
+		transport, ok := newHTTPClient(newOptions(tt.opts)).Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("%s: not an *http.Transport", tt.name)
+		}
+		// HTTP_PROXY & co. only work as long as this is still set
+		if transport.Proxy == nil {
+			t.Errorf("%s: transport lost ProxyFromEnvironment", tt.name)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package feed
+
+import (
+	"context"
+	"errors"
+)
+
+// FetchFeedConditional is FetchFeed for callers that keep their own
+// validators instead of using WithCache. it sends etag & lastModified as
+// If-None-Match / If-Modified-Since (either may be empty) and hands back the
+// ones to send next time. on a 304 notModified is true & posts is nil.
+func FetchFeedConditional(url, etag, lastModified string, opts ...Option) (posts []BlogPost, newETag, newLastModified string, notModified bool, err error) {
+	return NewFetcher(opts...).FetchFeedConditional(url, etag, lastModified)
+}
+
+func (f *Fetcher) FetchFeedConditional(url, etag, lastModified string) (posts []BlogPost, newETag, newLastModified string, notModified bool, err error) {
+	return f.FetchFeedConditionalContext(context.Background(), url, etag, lastModified)
+}
+
+// the Fetcher's own cache is left alone either way, the validators here are the caller's
+func (f *Fetcher) FetchFeedConditionalContext(ctx context.Context, url, etag, lastModified string) (posts []BlogPost, newETag, newLastModified string, notModified bool, err error) {
+	newETag, newLastModified = etag, lastModified
+
+	_, posts, err = f.fetchWith(ctx, url, f.opts, func(ctx context.Context) ([]byte, error) {
+		d, err := f.download(ctx, url, CacheEntry{ETag: etag, LastModified: lastModified}, f.opts)
+		if d == nil {
+			return nil, err
+		}
+		if err == nil {
+			newETag, newLastModified = d.etag, d.lastModified
+			return d.body, nil
+		}
+		// a 304 mostly leaves these out, which means keep the old ones
+		if d.etag != "" {
+			newETag = d.etag
+		}
+		if d.lastModified != "" {
+			newLastModified = d.lastModified
+		}
+		return nil, err
+	})
+
+	switch {
+	case errors.Is(err, ErrNotModified):
+		return nil, newETag, newLastModified, true, nil
+	case err != nil:
+		return nil, etag, lastModified, false, err
+	}
+	return posts, newETag, newLastModified, false, nil
+}
@@ -0,0 +1,67 @@
+package feed
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+func TestFetchFeedConditional(t *testing.T) {
+	modified := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	stamp := modified.Format(http.TimeFormat)
+	later := modified.Add(time.Hour).Format(http.TimeFormat)
+
+	byETag := feedtest.NewServer([]byte(postsFeed("tagged", 2)), feedtest.WithETag(`"v1"`))
+	defer byETag.Close()
+	byDate := feedtest.NewServer([]byte(postsFeed("dated", 2)), feedtest.WithLastModified(modified))
+	defer byDate.Close()
+	plain := feedtest.NewServer([]byte(postsFeed("plain", 2)))
+	defer plain.Close()
+	gone := feedtest.NewServer(nil, feedtest.WithStatus(http.StatusGone))
+	defer gone.Close()
+
+	tests := []struct {
+		name               string
+		url                string
+		etag, lastModified string
+
+		posts              int
+		notModified, fails bool
+		wantETag, wantLM   string
+	}{
+		{"first fetch", byETag.URL, "", "", 2, false, false, `"v1"`, ""},
+		{"same etag", byETag.URL, `"v1"`, "", 0, true, false, `"v1"`, ""},
+		{"stale etag", byETag.URL, `"v0"`, "", 2, false, false, `"v1"`, ""},
+		{"first fetch by date", byDate.URL, "", "", 2, false, false, "", stamp},
+		{"same date", byDate.URL, "", stamp, 0, true, false, "", stamp},
+		// the server's Last-Modified on the 304 wins over the caller's guess
+		{"date since", byDate.URL, "", later, 0, true, false, "", stamp},
+		// a server without validators hands none back, even if the caller had some
+		{"no validators", plain.URL, `"v1"`, stamp, 2, false, false, "", ""},
+		{"error keeps the old ones", gone.URL, `"v1"`, stamp, 0, false, true, `"v1"`, stamp},
+	}
+	for _, tt := range tests {
+		posts, etag, lastModified, notModified, err := FetchFeedConditional(tt.url, tt.etag, tt.lastModified)
+		if (err != nil) != tt.fails {
+			t.Errorf("%s: error %v", tt.name, err)
+		}
+		if len(posts) != tt.posts || notModified != tt.notModified {
+			t.Errorf("%s: got %d posts, notModified %v, want %d, %v", tt.name, len(posts), notModified, tt.posts, tt.notModified)
+		}
+		if etag != tt.wantETag || lastModified != tt.wantLM {
+			t.Errorf("%s: got validators %q %q, want %q %q", tt.name, etag, lastModified, tt.wantETag, tt.wantLM)
+		}
+	}
+
+	// the feed changing gets the new body & new validators past the old ETag
+	byETag.SetBody([]byte(postsFeed("tagged", 3)))
+	posts, etag, _, notModified, err := FetchFeedConditional(byETag.URL, `"v1"`, "")
+	if err != nil || notModified || len(posts) != 3 {
+		t.Fatalf("after a change: %d posts, notModified %v, error %v", len(posts), notModified, err)
+	}
+	if etag == `"v1"` || etag != byETag.ETag() {
+		t.Errorf("after a change: ETag %q, want the server's new %q", etag, byETag.ETag())
+	}
+}
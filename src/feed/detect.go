@@ -0,0 +1,126 @@
+package feed
+
+import (
+	"strings"
+	"unicode"
+)
+
+// a tiny language guesser for WithLanguageDetection. it's no real n-gram
+// model: non-Latin scripts mostly give themselves away, and Latin-script
+// text is scored on a couple dozen of each language's most common short
+// words. good enough to tell English from German in a paragraph, useless on
+// a three-word title, and it only knows the languages listed here.
+
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	// kana before han, since japanese uses both
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	// can't tell russian from ukrainian or bulgarian like this
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Arabic, "ar"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "for", "was", "with", "this", "are", "on", "you", "have", "but", "not", "be", "they", "from", "which", "would", "there", "their", "what", "about"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "ein", "eine", "zu", "den", "mit", "sich", "auf", "für", "auch", "es", "dem", "von", "wir", "ich", "sie", "werden", "wird", "aber", "oder", "nach", "bei"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "du", "que", "pour", "dans", "qui", "pas", "sur", "au", "avec", "ce", "sont", "nous", "vous", "mais", "ou", "cette", "leur", "aux", "été", "je"},
+	"es": {"el", "los", "las", "y", "que", "del", "una", "por", "con", "para", "es", "se", "su", "al", "lo", "como", "más", "pero", "sus", "este", "está", "muy", "también", "fue", "hay", "sin", "yo"},
+	"it": {"il", "di", "che", "è", "gli", "della", "per", "una", "sono", "non", "con", "del", "nel", "anche", "alla", "questo", "ma", "come", "più", "dei", "delle", "ho", "molto", "essere", "sul", "lo", "io"},
+	"pt": {"o", "os", "as", "que", "não", "uma", "do", "da", "dos", "das", "em", "para", "com", "por", "mais", "como", "mas", "foi", "ao", "ele", "isso", "está", "também", "você", "muito", "já", "eu"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "zijn", "op", "te", "met", "voor", "ook", "maar", "er", "wij", "ik", "je", "hij", "naar", "worden", "wordt", "deze", "bij", "dit", "zo"},
+}
+
+var stopwordLangs = func() map[string][]string {
+	byWord := make(map[string][]string)
+	for lang, words := range stopwords {
+		for _, word := range words {
+			byWord[word] = append(byWord[word], lang)
+		}
+	}
+	return byWord
+}()
+
+const (
+	// fewer stopword hits than this & it's a coin toss
+	minStopwordHits = 3
+	// the winner needs this many times the runner-up's hits
+	minStopwordLead = 1.5
+)
+
+// detectLanguage guesses text's language as a two-letter code, or "" when
+// it can't say with any confidence
+func detectLanguage(text string) string {
+	if lang := detectScript(text); lang != "" {
+		return lang
+	}
+
+	hits := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		for _, lang := range stopwordLangs[word] {
+			hits[lang]++
+		}
+	}
+
+	best, bestHits, runnerUp := "", 0, 0
+	for lang, n := range hits {
+		if n > bestHits {
+			best, bestHits = lang, n
+		}
+	}
+	// a tie makes the runner-up as good as the winner, so ties give ""
+	for lang, n := range hits {
+		if lang != best && n > runnerUp {
+			runnerUp = n
+		}
+	}
+
+	if bestHits < minStopwordHits || float64(bestHits) < minStopwordLead*float64(runnerUp) {
+		return ""
+	}
+	return best
+}
+
+// detectScript names the language of a non-Latin script that makes up at
+// least half the letters in text
+func detectScript(text string) string {
+	letters := 0
+	counts := make([]int, len(scriptLanguages))
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for i, script := range scriptLanguages {
+			if unicode.Is(script.table, r) {
+				counts[i]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	// japanese is kana & kanji mixed, any real amount of kana settles it
+	kana := counts[0] + counts[1]
+	if kana*5 >= letters {
+		return "ja"
+	}
+	for i, n := range counts {
+		if n*2 >= letters {
+			return scriptLanguages[i].lang
+		}
+	}
+	return ""
+}
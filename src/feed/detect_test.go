@@ -0,0 +1,66 @@
+package feed
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"The release is out and it is the biggest one that we have shipped this year, with a lot of work from the community.", "en"},
+		{"Die neue Version ist da und sie ist die größte, die wir in diesem Jahr veröffentlicht haben. Auch die Dokumentation wird besser.", "de"},
+		{"Nous avons publié une nouvelle version cette semaine, et elle est plus rapide que la précédente pour les grands projets.", "fr"},
+		{"今日は新しいバージョンをリリースしました。", "ja"},
+		{"Сегодня мы выпустили новую версию.", "ru"},
+		// too short to say anything about
+		{"Release notes", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := detectLanguage(tt.text); got != tt.want {
+			t.Errorf("detectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+const mixedLanguageFeed = `<rss version="2.0"><channel><title>mixed</title>
+<item><title>english</title><link>https://mixed.example.com/en</link><pubDate>Tue, 02 Jan 2024 10:00:00 GMT</pubDate>
+<description>This is the first post on the blog and it is about the things that we have been working on.</description></item>
+<item><title>deutsch</title><link>https://mixed.example.com/de</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate>
+<description>Das ist der erste Beitrag auf dem Blog und es geht um die Dinge, an denen wir auch gearbeitet haben.</description></item>
+<item><title>short</title><link>https://mixed.example.com/short</link><pubDate>Sun, 31 Dec 2023 10:00:00 GMT</pubDate></item>
+</channel></rss>`
+
+func TestLanguageDetection(t *testing.T) {
+	tests := []struct {
+		name string
+		feed string
+		opts []Option
+		want map[string]string
+	}{
+		{"off", mixedLanguageFeed, nil, map[string]string{"english": "", "deutsch": "", "short": ""}},
+		{"on", mixedLanguageFeed, []Option{WithLanguageDetection()}, map[string]string{"english": "en", "deutsch": "de", "short": ""}},
+		{"german only", mixedLanguageFeed, []Option{WithLanguageDetection(), WithLanguageAllowlist(false, "de")}, map[string]string{"deutsch": "de"}},
+		{"german & unsure", mixedLanguageFeed, []Option{WithLanguageDetection(), WithLanguageAllowlist(true, "de")}, map[string]string{"deutsch": "de", "short": ""}},
+		// a declared language beats any guessing
+		{"declared", strings.Replace(mixedLanguageFeed, "<title>mixed</title>", "<title>mixed</title><language>en</language>", 1),
+			[]Option{WithLanguageDetection()}, map[string]string{"english": "en", "deutsch": "en", "short": "en"}},
+	}
+	for _, tt := range tests {
+		posts, err := ParseFeed(strings.NewReader(tt.feed), tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := make(map[string]string)
+		for _, post := range posts {
+			got[post.Title] = post.Language
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package feed
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+
+	// ErrFeedTooLarge means a response went over WithMaxBodyBytes, either by
+	// announcing it up front in Content-Length or while we were reading it.
+	ErrFeedTooLarge = errors.New("feed too large")
+
+	// ErrBlockedByCloudflare means we got a Cloudflare bot challenge instead
+	// of the feed. The feed is probably fine, it just won't talk to bots.
+	ErrBlockedByCloudflare = errors.New("blocked by cloudflare challenge")
+
+	// ErrEmptyFeed means the server said 200 but sent nothing (or only
+	// whitespace) back, usually a misconfigured server rather than a bad feed.
+	ErrEmptyFeed = errors.New("empty feed body")
+
+	// ErrFeedGone means the server answered 410 Gone: the feed was removed
+	// on purpose and should be unsubscribed rather than retried.
+	ErrFeedGone = errors.New("feed gone")
+
+	// ErrTruncatedFeed means the document stops partway through, usually a
+	// dropped connection. WithLenient keeps the items before the cut instead.
+	ErrTruncatedFeed = errors.New("feed truncated")
+
+	// ErrNotCached means Reparse had no cached body for the feed, either
+	// because caching is off or it hasn't been fetched yet.
+	ErrNotCached = errors.New("feed not cached")
+
+	// ErrBudgetExceeded means a multi-feed fetch used up its WithTotalBudget
+	// byte allowance before this feed finished downloading.
+	ErrBudgetExceeded = errors.New("total fetch budget exceeded")
+
+	// ErrNotModified means the server answered 304 but we had no cached copy
+	// to fall back on, so there's nothing to parse.
+	ErrNotModified = errors.New("feed not modified")
+
+	// ErrNoItemsFound means the document parsed fine but has no items or
+	// entries in it. The FeedInfo still comes back alongside it.
+	ErrNoItemsFound = errors.New("no items found in feed")
+)
+
+// FeedHTTPError is a response that wasn't a feed, e.g. a 404 or a 500.
+// A 410 is one too, and also matches ErrFeedGone.
+type FeedHTTPError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *FeedHTTPError) Error() string {
+	return fmt.Sprintf("feed %s: %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func (e *FeedHTTPError) Unwrap() error {
+	if e.StatusCode == http.StatusGone {
+		return ErrFeedGone
+	}
+	return nil
+}
+
+// FeedParseError is a document that couldn't be read as a feed: bad XML,
+// nothing there, cut off partway. Err says which, so errors.Is still
+// matches ErrEmptyFeed, ErrTruncatedFeed & ErrNoItemsFound through it.
+type FeedParseError struct {
+	// URL is empty for documents that didn't come from a fetch
+	URL string
+	// Line is where the XML went wrong, 0 when that isn't the problem
+	Line int
+	Err  error
+}
+
+func (e *FeedParseError) Error() string {
+	if e.URL == "" {
+		return e.Err.Error()
+	}
+	return e.URL + ": " + e.Err.Error()
+}
+
+func (e *FeedParseError) Unwrap() error {
+	return e.Err
+}
+
+func parseError(err error) *FeedParseError {
+	pe := &FeedParseError{Err: err}
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		pe.Line = syntaxErr.Line
+	}
+	return pe
+}
+
+// withURL says which feed a parse error came from. anything else (a
+// callback's own error, say) gets the usual prefix.
+func withURL(err error, url string) error {
+	var pe *FeedParseError
+	if errors.As(err, &pe) && pe.URL == "" {
+		pe.URL = url
+		return err
+	}
+	return fmt.Errorf("%s: %w", url, err)
+}
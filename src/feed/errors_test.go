@@ -0,0 +1,94 @@
+package feed
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+func TestHTTPStatusErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		gone   bool
+	}{
+		{http.StatusGone, true},
+		// a 404 might just be a server having a bad day
+		{http.StatusNotFound, false},
+		{http.StatusInternalServerError, false},
+		{http.StatusForbidden, false},
+	}
+	for _, tt := range tests {
+		srv := feedtest.NewServer(nil, feedtest.WithStatus(tt.status))
+		_, err := FetchFeed(srv.URL)
+		srv.Close()
+
+		if got := errors.Is(err, ErrFeedGone); got != tt.gone {
+			t.Errorf("%d: errors.Is(%v, ErrFeedGone) = %v, want %v", tt.status, err, got, tt.gone)
+		}
+		var httpErr *FeedHTTPError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != tt.status || httpErr.URL != srv.URL {
+			t.Errorf("%d: got %#v, want a FeedHTTPError for it", tt.status, err)
+		}
+	}
+}
+
+func TestFeedGoneNotRetried(t *testing.T) {
+	srv := feedtest.NewServer(nil, feedtest.WithStatus(http.StatusGone))
+	defer srv.Close()
+
+	if _, err := FetchFeed(srv.URL, WithRetries(3, 0)); !errors.Is(err, ErrFeedGone) {
+		t.Fatalf("err %v, want ErrFeedGone", err)
+	}
+	if got := srv.Requests(); got != 1 {
+		t.Errorf("a dead feed got asked %d times", got)
+	}
+}
+
+func TestCloudflareChallenge(t *testing.T) {
+	page, err := os.ReadFile("testdata/cloudflare_challenge.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		body    []byte
+		blocked bool
+	}{
+		{"challenge page", http.StatusServiceUnavailable, map[string]string{"Server": "cloudflare"}, page, true},
+		{"managed challenge", http.StatusForbidden, map[string]string{"Server": "cloudflare"}, page, true},
+		{"cf-mitigated header", http.StatusForbidden, map[string]string{"cf-mitigated": "challenge"}, []byte("<html></html>"), true},
+		// cloudflare passing on the origin's own outage isn't a challenge
+		{"plain 503 through cloudflare", http.StatusServiceUnavailable, map[string]string{"Server": "cloudflare"}, []byte("<html><title>Down for maintenance</title></html>"), false},
+		{"challenge page, not from cloudflare", http.StatusServiceUnavailable, nil, page, false},
+	}
+	for _, tt := range tests {
+		var requests atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			for k, v := range tt.headers {
+				w.Header().Set(k, v)
+			}
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(tt.status)
+			w.Write(tt.body)
+		}))
+
+		_, err := FetchFeed(srv.URL, WithRetries(2, time.Millisecond))
+		if got := errors.Is(err, ErrBlockedByCloudflare); got != tt.blocked {
+			t.Errorf("%s: err %v, blocked %v, want %v", tt.name, err, got, tt.blocked)
+		}
+		if n := requests.Load(); tt.blocked && n != 1 {
+			t.Errorf("%s: a challenge got asked %d times", tt.name, n)
+		}
+		srv.Close()
+	}
+}
@@ -0,0 +1,146 @@
+package feed
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// helpers for digging specific things out of item HTML. none of this needs a
+// real parser, we only ever look at single tags.
+
+var (
+	imgTagRegex  = regexp.MustCompile(`(?i)<img\b[^>]*>`)
+	linkTagRegex = regexp.MustCompile(`(?i)<link\b[^>]*>`)
+	anchorRegex  = regexp.MustCompile(`(?i)<a\b[^>]*>`)
+	attrRegex    = regexp.MustCompile(`([a-zA-Z][\w:-]*)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+)
+
+func tagAttrs(tag string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range attrRegex.FindAllStringSubmatch(tag, -1) {
+		value := strings.Trim(m[2], `"'`)
+		attrs[strings.ToLower(m[1])] = html.UnescapeString(value)
+	}
+	return attrs
+}
+
+// resolveURL makes ref absolute against base, when base is any use
+func resolveURL(ref, base string) string {
+	ref = strings.TrimSpace(ref)
+	refURL, err := url.Parse(ref)
+	if err != nil || refURL.IsAbs() {
+		return ref
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil || !baseURL.IsAbs() {
+		return ref
+	}
+
+	return baseURL.ResolveReference(refURL).String()
+}
+
+// firstImage finds the first <img> that's an actual picture, skipping
+// inline data: URIs and 1x1 tracking pixels
+func firstImage(content, base string) string {
+	for _, tag := range imgTagRegex.FindAllString(content, -1) {
+		attrs := tagAttrs(tag)
+
+		src := strings.TrimSpace(attrs["src"])
+		if src == "" || strings.HasPrefix(strings.ToLower(src), "data:") {
+			continue
+		}
+		if isTinyDimension(attrs["width"]) || isTinyDimension(attrs["height"]) {
+			continue
+		}
+
+		return resolveURL(src, base)
+	}
+
+	return ""
+}
+
+func isTinyDimension(value string) bool {
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "px"))
+	return err == nil && n <= 1
+}
+
+// canonicalLink finds a <link rel="canonical"> in republished content,
+// pointing at wherever the article first went up
+func canonicalLink(content, base string) string {
+	for _, tag := range linkTagRegex.FindAllString(content, -1) {
+		attrs := tagAttrs(tag)
+
+		isCanonical := false
+		for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+			isCanonical = isCanonical || rel == "canonical"
+		}
+		if !isCanonical || strings.TrimSpace(attrs["href"]) == "" {
+			continue
+		}
+
+		return resolveURL(attrs["href"], base)
+	}
+
+	return ""
+}
+
+// Preview is a ready-made link card for a post, only filled in with
+// WithPreviews. Any field can be empty when the content doesn't have one.
+type Preview struct {
+	// Text is the first paragraph of the content as plain text
+	Text string
+	// Image is the first real image in the content
+	Image string
+	// Link is the first link pointing off the post's own site
+	Link string
+}
+
+func getPreview(item Item, link string, o *options) Preview {
+	var p Preview
+	for _, content := range []string{item.Encoded, item.Content.html(), item.Description} {
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+
+		// paragraph mode already knows where paragraphs end
+		po := *o
+		po.paragraphs = true
+		text, _, _ := strings.Cut(cleanHTML(content, &po), "\n\n")
+		p.Text = strings.TrimSpace(text)
+		p.Image = firstImage(content, link)
+		p.Link = firstOutboundLink(content, link)
+		return p
+	}
+	return p
+}
+
+// firstOutboundLink finds the first <a> leading somewhere other than the
+// post's own host, skipping anchors, mailto: & the like
+func firstOutboundLink(content, base string) string {
+	home := ""
+	if u, err := url.Parse(base); err == nil {
+		home = strings.ToLower(u.Hostname())
+	}
+
+	for _, tag := range anchorRegex.FindAllString(content, -1) {
+		href := strings.TrimSpace(tagAttrs(tag)["href"])
+		if href == "" || strings.HasPrefix(href, "#") {
+			continue
+		}
+
+		u, err := url.Parse(resolveURL(href, base))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		if strings.ToLower(u.Hostname()) == home {
+			continue
+		}
+		return u.String()
+	}
+
+	return ""
+}
@@ -0,0 +1,109 @@
+package feed
+
+import (
+	"testing"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+func TestContentImages(t *testing.T) {
+	const content = `<content:encoded><![CDATA[<p><img src="https://tracker.example.com/pixel.gif" width="1" height="1">
+<img src="data:image/png;base64,iVBORw0KGgo=" alt="">Some words, then the real picture:</p>
+<p><img class="wp-image" src="/uploads/2024/photo.jpg" width="800px" alt="a photo"></p>
+<p><img src="https://example.com/second.jpg"></p>]]></content:encoded>`
+
+	tests := []struct {
+		name string
+		item string
+		opts []Option
+		want string
+	}{
+		{"off by default", content, nil, ""},
+		{"first real image, resolved", content, []Option{WithContentImages()}, "https://example.com/uploads/2024/photo.jpg"},
+		{"thumbnail wins", `<media:thumbnail url="https://example.com/thumb.jpg"/>` + content, []Option{WithContentImages()}, "https://example.com/thumb.jpg"},
+		{"description", `<description>&lt;img src="https://example.com/in-description.png"&gt;</description>`, []Option{WithContentImages()}, "https://example.com/in-description.png"},
+		{"only pixels", `<description>&lt;img src="https://tracker.example.com/p.gif" height="0"&gt;</description>`, []Option{WithContentImages()}, ""},
+	}
+	for _, tt := range tests {
+		body := `<item><title>Post</title><link>https://example.com/2024/post</link>` + tt.item + `</item>`
+		post := parseItemXML(t, body, tt.opts...)
+		if post.Image != tt.want {
+			t.Errorf("%s: image %q, want %q", tt.name, post.Image, tt.want)
+		}
+	}
+}
+
+func TestContentCanonical(t *testing.T) {
+	syndicated := func(site string) string {
+		return `<rss version="2.0"><channel><title>` + site + `</title><link>https://` + site + `.example.com/</link>
+<item><title>The article</title><link>https://` + site + `.example.com/reposted/article</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate>
+<description><![CDATA[<link rel="canonical" href="https://original.example.com/2024/article"><p>The article, republished.</p>]]></description></item>
+<item><title>Their own</title><link>https://` + site + `.example.com/own</link><pubDate>Tue, 02 Jan 2024 10:00:00 GMT</pubDate>
+<description><![CDATA[<link rel="stylesheet" href="/style.css"><p>Nothing canonical here.</p>]]></description></item>
+</channel></rss>`
+	}
+	a := feedtest.NewServer([]byte(syndicated("a")))
+	defer a.Close()
+	b := feedtest.NewServer([]byte(syndicated("b")))
+	defer b.Close()
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		posts     int
+		canonical string
+	}{
+		{"off by default", nil, 4, ""},
+		{"merged", []Option{WithContentCanonical()}, 3, "https://original.example.com/2024/article"},
+	}
+	for _, tt := range tests {
+		posts := FetchAllFeeds([]string{a.URL, b.URL}, tt.opts...)
+		if len(posts) != tt.posts {
+			t.Errorf("%s: got %d posts, want %d", tt.name, len(posts), tt.posts)
+		}
+		for _, post := range posts {
+			want := ""
+			if post.Title == "The article" {
+				want = tt.canonical
+			}
+			if post.CanonicalURL != want {
+				t.Errorf("%s: %q has canonical %q, want %q", tt.name, post.Title, post.CanonicalURL, want)
+			}
+		}
+	}
+}
+
+func TestPreviews(t *testing.T) {
+	const content = `<description>Short teaser</description><content:encoded><![CDATA[<p>The <em>first</em> paragraph &amp; its
+second line, with <a href="#footnote">a footnote</a>.</p>
+<p><img src="https://tracker.example.com/pixel.gif" width="1" height="1">
+<a href="/2024/older-post">an older post of ours</a>, <a href="mailto:me@example.com">mail</a>,
+<a href="https://example.com/about">the about page</a> &amp; finally
+<a href="https://elsewhere.example.org/article?id=7">someone else's article</a>.</p>
+<figure><img src="/uploads/card.jpg" alt="card"></figure>
+<p><a href="https://third.example.net/">another outbound link</a></p>]]></content:encoded>`
+
+	tests := []struct {
+		name string
+		item string
+		opts []Option
+		want Preview
+	}{
+		{"off by default", content, nil, Preview{}},
+		{"rich content", content, []Option{WithPreviews()}, Preview{
+			Text:  "The first paragraph & its second line, with a footnote.",
+			Image: "https://example.com/uploads/card.jpg",
+			Link:  "https://elsewhere.example.org/article?id=7",
+		}},
+		{"description only", `<description>&lt;p&gt;Just a teaser.&lt;/p&gt;&lt;p&gt;And more.&lt;/p&gt;</description>`, []Option{WithPreviews()}, Preview{
+			Text: "Just a teaser.",
+		}},
+		{"no content", ``, []Option{WithPreviews()}, Preview{}},
+	}
+	for _, tt := range tests {
+		post := parseItemXML(t, `<item><title>Post</title><link>https://example.com/2024/post</link>`+tt.item+`</item>`, tt.opts...)
+		if post.Preview != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, post.Preview, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package feed
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// the <link rel="icon"> is in the <head>, nobody needs the rest of the page
+const maxFaviconPageBytes = 256 << 10
+
+// siteFavicon is where browsers look when a page doesn't say: /favicon.ico
+// at the root of the site. "" unless site is an absolute http(s) URL.
+func siteFavicon(site string) string {
+	u, err := url.Parse(strings.TrimSpace(site))
+	if err != nil || u.Host == "" || u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + "/favicon.ico"
+}
+
+// pageIcon finds the first <link rel="icon"> (or "shortcut icon") in a page
+func pageIcon(page, base string) string {
+	for _, tag := range linkTagRegex.FindAllString(page, -1) {
+		attrs := tagAttrs(tag)
+
+		isIcon := false
+		for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+			isIcon = isIcon || rel == "icon"
+		}
+		if href := strings.TrimSpace(attrs["href"]); isIcon && href != "" {
+			return resolveURL(href, base)
+		}
+	}
+
+	return ""
+}
+
+// lookupFavicon fetches the site's homepage for its <link rel="icon">,
+// falling back to /favicon.ico when the page won't load or doesn't say.
+// the homepage is often on another host than the feed, so o should have
+// been through forTarget.
+func (f *Fetcher) lookupFavicon(ctx context.Context, site string, o *options) string {
+	fallback := siteFavicon(site)
+	if fallback == "" {
+		return ""
+	}
+
+	req, err := f.newRequest(ctx, http.MethodGet, site, CacheEntry{}, o)
+	if err != nil {
+		return fallback
+	}
+	resp, err := f.do(ctx, req, o)
+	if err != nil {
+		log.Printf("warn: looking up favicon for %s: %v", site, err)
+		return fallback
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fallback
+	}
+
+	// cutting the page off at the limit is fine, the head is long done by then
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxFaviconPageBytes))
+	if err != nil {
+		return fallback
+	}
+
+	// a redirect (http -> https, say) moves the base the page's links are relative to
+	if icon := pageIcon(string(page), resp.Request.URL.String()); icon != "" {
+		return icon
+	}
+	return fallback
+}
+
+// WithFaviconLookup fills in FeedInfo.FaviconURL from the site's own
+// <link rel="icon"> instead of guessing /favicon.ico. It costs a request
+// to the feed's homepage on every fetch, so it's off by default.
+func WithFaviconLookup() Option {
+	return func(o *options) {
+		o.faviconLookup = true
+	}
+}
@@ -0,0 +1,87 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestPageIcon(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{"relative", `<head><link rel="icon" href="/img/fav.png"></head>`, "https://example.com/img/fav.png"},
+		{"shortcut icon", `<link rel="Shortcut Icon" href="fav.ico">`, "https://example.com/blog/fav.ico"},
+		{"first icon wins", `<link rel="stylesheet" href="a.css"><link rel="icon" href="one.png"><link rel="icon" href="two.png">`, "https://example.com/blog/one.png"},
+		{"absolute", `<link href="https://cdn.example.net/i.png" rel="icon">`, "https://cdn.example.net/i.png"},
+		{"apple touch only", `<link rel="apple-touch-icon" href="touch.png">`, ""},
+		{"no href", `<link rel="icon">`, ""},
+		{"none", `<html><head><title>hi</title></head></html>`, ""},
+	}
+	for _, tt := range tests {
+		if got := pageIcon(tt.page, "https://example.com/blog/"); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSiteFavicon(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/blog/post?x=1": "https://example.com/favicon.ico",
+		"http://example.com:8080":           "http://example.com:8080/favicon.ico",
+		"ftp://example.com/":                "",
+		"/relative/path":                    "",
+		"":                                  "",
+	}
+	for site, want := range tests {
+		if got := siteFavicon(site); got != want {
+			t.Errorf("siteFavicon(%q) = %q, want %q", site, got, want)
+		}
+	}
+}
+
+func TestFaviconLookup(t *testing.T) {
+	home := newHeaderServer(`<html><head><link rel="icon" href="/static/icon.png"></head></html>`)
+	defer home.Close()
+
+	body := fmt.Sprintf(`<rss version="2.0"><channel><title>Blog</title><link>%s/</link>
+<item><title>Post</title><link>%s/1</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate></item>
+</channel></rss>`, home.URL, home.URL)
+	feedSrv := newHeaderServer(body)
+	defer feedSrv.Close()
+
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+		// how many requests the homepage should see
+		homeRequests int
+	}{
+		{"default guesses", nil, home.URL + "/favicon.ico", 0},
+		{"lookup", []Option{WithFaviconLookup()}, home.URL + "/static/icon.png", 1},
+	}
+	for _, tt := range tests {
+		before := len(home.seen())
+		opts := append([]Option{WithHeader("Authorization", "Bearer secret")}, tt.opts...)
+		info, _, err := NewFetcher(opts...).FetchFeedInfo(context.Background(), feedSrv.URL)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if info.FaviconURL != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, info.FaviconURL, tt.want)
+		}
+
+		seen := home.seen()[before:]
+		if len(seen) != tt.homeRequests {
+			t.Errorf("%s: homepage got %d requests, want %d", tt.name, len(seen), tt.homeRequests)
+		}
+		// the homepage is on another host than the feed
+		for _, auth := range seen {
+			if auth != "" {
+				t.Errorf("%s: homepage got Authorization %q", tt.name, auth)
+			}
+		}
+	}
+}
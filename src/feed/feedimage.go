@@ -0,0 +1,61 @@
+package feed
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FeedImage is a feed's icon or logo along with the shape it's meant to be
+// shown at, so a sidebar can take the icon & a header the logo without
+// guessing.
+type FeedImage struct {
+	URL string
+	// Width & Height come from the RSS <image>, 0 when not given
+	Width  int
+	Height int
+	// Aspect is the intended width / height: atom says 1 for <icon> & 2 for
+	// <logo>, an RSS <image> gets it from its own size. 0 means unknown.
+	Aspect float64
+}
+
+// atom's intended shapes, RFC 4287 4.2.5 & 4.2.8
+const (
+	atomIconAspect = 1
+	atomLogoAspect = 2
+)
+
+func getFeedImages(feed Feed) (icon, logo FeedImage) {
+	if url := strings.TrimSpace(feed.Icon); url != "" {
+		icon = FeedImage{URL: url, Aspect: atomIconAspect}
+	}
+	if url := strings.TrimSpace(feed.Logo); url != "" {
+		logo = FeedImage{URL: url, Aspect: atomLogoAspect}
+		return icon, logo
+	}
+
+	img := feed.Channel.Image
+	if url := strings.TrimSpace(img.URL); url != "" {
+		logo = FeedImage{URL: url}
+		// junk sizes just stay unknown
+		logo.Width, _ = strconv.Atoi(strings.TrimSpace(img.Width))
+		logo.Height, _ = strconv.Atoi(strings.TrimSpace(img.Height))
+		if logo.Width > 0 && logo.Height > 0 {
+			logo.Aspect = float64(logo.Width) / float64(logo.Height)
+		}
+	}
+	return icon, logo
+}
+
+// resolveImages makes relative icon & logo URLs absolute against base,
+// keeping IconURL & LogoURL in step
+func (fi *FeedInfo) resolveImages(base string) {
+	// resolving "" would hand back base itself
+	if fi.Icon.URL != "" {
+		fi.Icon.URL = resolveURL(fi.Icon.URL, base)
+	}
+	if fi.Logo.URL != "" {
+		fi.Logo.URL = resolveURL(fi.Logo.URL, base)
+	}
+	fi.IconURL = fi.Icon.URL
+	fi.LogoURL = fi.Logo.URL
+}
@@ -0,0 +1,92 @@
+package feed
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+const (
+	rssImageFeed = `<rss version="2.0"><channel><title>Branded</title><link>https://branded.example.com/</link>
+<image><url>https://branded.example.com/logo.png</url><title>Branded</title><link>https://branded.example.com/</link><width>144</width><height>72</height></image>
+<item><title>Post</title><link>https://branded.example.com/1</link></item>
+</channel></rss>`
+	atomImageFeed = `<feed xmlns="http://www.w3.org/2005/Atom"><title>Branded</title>
+<link rel="alternate" href="https://branded.example.com/"/>
+<icon>https://branded.example.com/icon.png</icon><logo>https://branded.example.com/logo.png</logo>
+<entry><title>Post</title><id>urn:1</id><link href="https://branded.example.com/1"/></entry>
+</feed>`
+)
+
+func TestFeedImages(t *testing.T) {
+	tests := []struct {
+		name       string
+		feed       string
+		icon, logo string
+		small      string
+		large      string
+	}{
+		{"rss image", rssImageFeed, "", "https://branded.example.com/logo.png", "https://branded.example.com/logo.png", "https://branded.example.com/logo.png"},
+		{"atom icon & logo", atomImageFeed, "https://branded.example.com/icon.png", "https://branded.example.com/logo.png", "https://branded.example.com/icon.png", "https://branded.example.com/logo.png"},
+		{"atom icon only", strings.Replace(atomImageFeed, "<logo>https://branded.example.com/logo.png</logo>", "", 1),
+			"https://branded.example.com/icon.png", "", "https://branded.example.com/icon.png", "https://branded.example.com/icon.png"},
+		{"no images", `<rss version="2.0"><channel><title>Plain</title><item><title>Post</title><link>https://plain.example.com/1</link></item></channel></rss>`, "", "", "", ""},
+	}
+	for _, tt := range tests {
+		info, _, err := ParseFeedInfo(strings.NewReader(tt.feed))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if info.IconURL != tt.icon || info.LogoURL != tt.logo {
+			t.Errorf("%s: icon %q, logo %q, want %q, %q", tt.name, info.IconURL, info.LogoURL, tt.icon, tt.logo)
+		}
+		if got := info.ImageURL(true); got != tt.small {
+			t.Errorf("%s: small image %q, want %q", tt.name, got, tt.small)
+		}
+		if got := info.ImageURL(false); got != tt.large {
+			t.Errorf("%s: large image %q, want %q", tt.name, got, tt.large)
+		}
+	}
+}
+
+func TestFeedImageSizes(t *testing.T) {
+	tests := []struct {
+		name       string
+		feed       string
+		icon, logo FeedImage
+	}{
+		{"atom icon & logo", `<feed xmlns="http://www.w3.org/2005/Atom" xml:base="https://branded.example.com/blog/"><title>Branded</title>
+<icon>/favicon-32.png</icon><logo>images/banner.png</logo>
+<entry><title>Post</title><id>urn:1</id><link href="https://branded.example.com/1"/></entry></feed>`,
+			FeedImage{URL: "https://branded.example.com/favicon-32.png", Aspect: 1}, FeedImage{URL: "https://branded.example.com/blog/images/banner.png", Aspect: 2}},
+		{"rss image size", rssImageFeed, FeedImage{}, FeedImage{URL: "https://branded.example.com/logo.png", Width: 144, Height: 72, Aspect: 2}},
+		{"rss image, junk size", strings.Replace(rssImageFeed, "<height>72</height>", "<height>tall</height>", 1),
+			FeedImage{}, FeedImage{URL: "https://branded.example.com/logo.png", Width: 144}},
+	}
+	for _, tt := range tests {
+		info, _, err := ParseFeedInfo(strings.NewReader(tt.feed))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if info.Icon != tt.icon || info.Logo != tt.logo {
+			t.Errorf("%s: got %+v & %+v, want %+v & %+v", tt.name, info.Icon, info.Logo, tt.icon, tt.logo)
+		}
+		if info.IconURL != info.Icon.URL || info.LogoURL != info.Logo.URL {
+			t.Errorf("%s: IconURL %q & LogoURL %q out of step", tt.name, info.IconURL, info.LogoURL)
+		}
+	}
+
+	// relative ones with no base in the feed resolve against where it came from
+	srv := feedtest.NewServer([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><title>Branded</title><icon>icon.png</icon><logo>/logo.png</logo>
+<entry><title>Post</title><id>urn:1</id><link href="https://branded.example.com/1"/></entry></feed>`))
+	defer srv.Close()
+	info, _, err := NewFetcher().FetchFeedInfo(context.Background(), srv.URL+"/feeds/atom.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.IconURL != srv.URL+"/feeds/icon.png" || info.LogoURL != srv.URL+"/logo.png" {
+		t.Errorf("fetched: icon %q, logo %q", info.IconURL, info.LogoURL)
+	}
+}
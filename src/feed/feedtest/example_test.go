@@ -0,0 +1,54 @@
+package feedtest_test
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/UW-UPL/harvest/src/feed"
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+const onePost = `<rss version="2.0"><channel><title>Example</title>
+<item><title>First</title><link>https://example.com/1</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate></item>
+</channel></rss>`
+
+const twoPosts = `<rss version="2.0"><channel><title>Example</title>
+<item><title>Second</title><link>https://example.com/2</link><pubDate>Tue, 02 Jan 2024 10:00:00 GMT</pubDate></item>
+<item><title>First</title><link>https://example.com/1</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate></item>
+</channel></rss>`
+
+// A caching Fetcher against a feed that gets a new post between polls.
+func Example() {
+	srv := feedtest.NewServer([]byte(onePost), feedtest.WithETag(`"v1"`))
+	defer srv.Close()
+
+	f := feed.NewFetcher(feed.WithCache())
+	posts, _ := f.FetchFeed(srv.URL)
+	fmt.Println(len(posts), posts[0].Title)
+
+	// unchanged: a 304, served from the cache
+	posts, _ = f.FetchFeed(srv.URL)
+	fmt.Println(len(posts), posts[0].Title)
+
+	srv.SetBody([]byte(twoPosts))
+	posts, _ = f.FetchFeed(srv.URL)
+	fmt.Println(len(posts), posts[0].Title)
+	fmt.Println(srv.Requests(), "requests")
+
+	// Output:
+	// 1 First
+	// 1 First
+	// 2 Second
+	// 3 requests
+}
+
+func ExampleWithStatus() {
+	srv := feedtest.NewServer(nil, feedtest.WithStatus(410))
+	defer srv.Close()
+
+	_, err := feed.FetchFeed(srv.URL)
+	fmt.Println(errors.Is(err, feed.ErrFeedGone))
+
+	// Output:
+	// true
+}
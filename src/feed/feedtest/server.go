@@ -0,0 +1,196 @@
+// Package feedtest serves feeds over a local httptest server, for examples &
+// tests of code built on harvest.
+package feedtest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is an httptest.Server that answers every path with one feed.
+// Close it when done, same as any httptest.Server.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	body     []byte
+	requests int
+	cfg      config
+}
+
+type config struct {
+	status       int
+	contentType  string
+	etag         string
+	lastModified time.Time
+	delay        time.Duration
+	gzip         bool
+}
+
+// Option tweaks how a Server responds.
+type Option func(*config)
+
+// WithStatus answers with code instead of 200, e.g. 404 or 503.
+func WithStatus(code int) Option {
+	return func(c *config) {
+		c.status = code
+	}
+}
+
+// WithContentType overrides the default "application/rss+xml".
+func WithContentType(contentType string) Option {
+	return func(c *config) {
+		c.contentType = contentType
+	}
+}
+
+// WithETag sends tag as the ETag, and a 304 to requests that already have it.
+func WithETag(tag string) Option {
+	return func(c *config) {
+		c.etag = tag
+	}
+}
+
+// WithLastModified sends t as Last-Modified, and a 304 to requests whose
+// If-Modified-Since isn't older.
+func WithLastModified(t time.Time) Option {
+	return func(c *config) {
+		c.lastModified = t.UTC().Truncate(time.Second)
+	}
+}
+
+// WithDelay waits d before answering, or until the client hangs up.
+func WithDelay(d time.Duration) Option {
+	return func(c *config) {
+		c.delay = d
+	}
+}
+
+// WithGzip gzips the body for clients that send Accept-Encoding: gzip.
+func WithGzip() Option {
+	return func(c *config) {
+		c.gzip = true
+	}
+}
+
+// NewServer starts a server handing out body.
+func NewServer(body []byte, opts ...Option) *Server {
+	s := &Server{
+		body: body,
+		cfg: config{
+			status:      http.StatusOK,
+			contentType: "application/rss+xml",
+		},
+	}
+	for _, opt := range opts {
+		opt(&s.cfg)
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// SetBody swaps the feed for the next requests, e.g. to add a post. A
+// changed feed gets new validators too, so a client holding the old ones
+// sees the new body instead of a 304: the ETag (if WithETag) becomes a hash
+// of body & Last-Modified (if WithLastModified) moves forward. Setting the
+// same body again leaves them alone.
+func (s *Server) SetBody(body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if bytes.Equal(body, s.body) {
+		return
+	}
+
+	s.body = body
+	if s.cfg.etag != "" {
+		sum := sha256.Sum256(body)
+		s.cfg.etag = fmt.Sprintf(`"%x"`, sum[:8])
+	}
+	if !s.cfg.lastModified.IsZero() {
+		// HTTP dates only go down to the second, so it has to move by at least one
+		next := time.Now().UTC().Truncate(time.Second)
+		if !next.After(s.cfg.lastModified) {
+			next = s.cfg.lastModified.Add(time.Second)
+		}
+		s.cfg.lastModified = next
+	}
+}
+
+// ETag is the ETag the server currently sends, "" without WithETag.
+func (s *Server) ETag() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg.etag
+}
+
+// Requests is how many requests the server has seen, HEADs included.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	body := s.body
+	cfg := s.cfg
+	s.mu.Unlock()
+
+	if cfg.delay > 0 {
+		timer := time.NewTimer(cfg.delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if cfg.etag != "" {
+		w.Header().Set("ETag", cfg.etag)
+	}
+	if !cfg.lastModified.IsZero() {
+		w.Header().Set("Last-Modified", cfg.lastModified.Format(http.TimeFormat))
+	}
+	if cfg.status == http.StatusOK && notModified(r, cfg) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", cfg.contentType)
+	if cfg.gzip && strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(body)
+		zw.Close()
+
+		w.Header().Set("Content-Encoding", "gzip")
+		body = buf.Bytes()
+	}
+
+	w.WriteHeader(cfg.status)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+func notModified(r *http.Request, cfg config) bool {
+	if cfg.etag != "" && r.Header.Get("If-None-Match") == cfg.etag {
+		return true
+	}
+	if cfg.lastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !cfg.lastModified.After(since)
+}
@@ -0,0 +1,130 @@
+package feedtest
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var body = []byte(`<rss version="2.0"><channel><title>t</title></channel></rss>`)
+
+func TestServer(t *testing.T) {
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		method   string
+		header   http.Header
+		status   int
+		wantBody bool
+		gzipped  bool
+	}{
+		{name: "plain", status: 200, wantBody: true},
+		{name: "status", opts: []Option{WithStatus(404)}, status: 404, wantBody: true},
+		{name: "etag match", opts: []Option{WithETag(`"a"`)}, header: http.Header{"If-None-Match": {`"a"`}}, status: 304},
+		{name: "etag mismatch", opts: []Option{WithETag(`"a"`)}, header: http.Header{"If-None-Match": {`"b"`}}, status: 200, wantBody: true},
+		{name: "not modified since", opts: []Option{WithLastModified(modified)}, header: http.Header{"If-Modified-Since": {modified.Format(http.TimeFormat)}}, status: 304},
+		{name: "modified since", opts: []Option{WithLastModified(modified)}, header: http.Header{"If-Modified-Since": {modified.Add(-time.Hour).Format(http.TimeFormat)}}, status: 200, wantBody: true},
+		{name: "error ignores validators", opts: []Option{WithStatus(503), WithETag(`"a"`)}, header: http.Header{"If-None-Match": {`"a"`}}, status: 503, wantBody: true},
+		{name: "gzip", opts: []Option{WithGzip()}, header: http.Header{"Accept-Encoding": {"gzip"}}, status: 200, wantBody: true, gzipped: true},
+		{name: "head", method: http.MethodHead, status: 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewServer(body, tt.opts...)
+			defer srv.Close()
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req, _ := http.NewRequest(method, srv.URL, nil)
+			for key, values := range tt.header {
+				req.Header[key] = values
+			}
+
+			// a plain Transport so gzip doesn't get undone behind our back
+			resp, err := (&http.Transport{DisableCompression: true}).RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			var r io.Reader = resp.Body
+			if tt.gzipped {
+				if resp.Header.Get("Content-Encoding") != "gzip" {
+					t.Fatal("body isn't gzipped")
+				}
+				if r, err = gzip.NewReader(resp.Body); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, _ := io.ReadAll(r)
+			if tt.wantBody && string(got) != string(body) {
+				t.Errorf("body = %q, want %q", got, body)
+			}
+			if !tt.wantBody && len(got) != 0 {
+				t.Errorf("body = %q, want none", got)
+			}
+			if srv.Requests() != 1 {
+				t.Errorf("Requests() = %d, want 1", srv.Requests())
+			}
+		})
+	}
+}
+
+func TestServerDelay(t *testing.T) {
+	srv := NewServer(body, WithDelay(time.Second))
+	defer srv.Close()
+
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	if _, err := client.Get(srv.URL); err == nil {
+		t.Fatal("expected a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("gave up after %v, the delay should have been abandoned", elapsed)
+	}
+}
+
+func TestSetBodyChangesValidators(t *testing.T) {
+	modified := time.Now().UTC().Truncate(time.Second)
+	srv := NewServer(body, WithETag(`"v1"`), WithLastModified(modified))
+	defer srv.Close()
+
+	srv.SetBody(body)
+	if srv.ETag() != `"v1"` {
+		t.Errorf("the same body changed the ETag to %s", srv.ETag())
+	}
+
+	srv.SetBody([]byte(`<rss version="2.0"><channel><title>new</title></channel></rss>`))
+	if srv.ETag() == `"v1"` {
+		t.Fatal("a new body kept the old ETag")
+	}
+
+	// either of the old validators would have got a 304 before
+	for _, header := range []http.Header{
+		{"If-None-Match": {`"v1"`}},
+		{"If-Modified-Since": {modified.Format(http.TimeFormat)}},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%v: status = %d, want 200", header, resp.StatusCode)
+		}
+	}
+}
@@ -1,191 +1,588 @@
 package feed
 
 import (
-	"encoding/xml"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
-	"html"
 	"io"
 	"log"
 	"net/http"
-	"regexp"
-	"sort"
 	"strings"
 	"sync"
-	"time"
 )
 
-var dateFormats = []string{
-	time.RFC1123Z,
-	time.RFC1123,
-	time.RFC3339,
-	time.RFC3339Nano,
-	"2006-01-02T15:04:05Z",
-	"2006-01-02 15:04:05 -0700",
-	"02 Jan 2006 15:04 -0700",
-	"Mon, 02 Jan 2006 15:04:05 GMT",
-	"02 Jan 2006 15:04 +0000",
-	"2006-01-02",
-	"January 2, 2006",
+// Fetcher holds the options & HTTP client shared by every fetch it makes.
+type Fetcher struct {
+	opts   *options
+	client *http.Client
+	// nil unless WithCache (or WithHeadProbe, or WithStore) is on
+	cache Store
+	// nil unless WithSourceTitleLookup is on
+	sources *sourceTitles
+	// nil unless WithParsedCache is on
+	parsed *parsedCache
+	// nil unless WithMaxConcurrentFetches, shared by every call on this Fetcher
+	slots chan struct{}
 }
 
-// We're rendering to markdown so to preserve formatting we need to strip out any markdown characters
-func stripMarkdown(input string) string {
-	invalidChars := []string{"*", "_", "#", "`", ">", "<", "[", "]", "(", ")", "!", "~", "|", "{", "}", "+"}
-	for _, char := range invalidChars {
-		input = strings.ReplaceAll(input, char, "")
+func NewFetcher(opts ...Option) *Fetcher {
+	o := newOptions(opts)
+	f := &Fetcher{
+		opts:   o,
+		client: newHTTPClient(o),
 	}
+	switch {
+	case o.store != nil:
+		f.cache = o.store
+	case o.cache || o.headProbe:
+		f.cache = newMemoryCache()
+	}
+	if o.sourceTitles {
+		f.sources = newSourceTitles()
+	}
+	if o.parsedCache {
+		f.parsed = newParsedCache()
+	}
+	if o.maxConcurrent > 0 {
+		f.slots = make(chan struct{}, o.maxConcurrent)
+	}
+
+	return f
+}
 
-	return input
+// FetchFeed returns the feed's posts in document order, it never sorts.
+// (FetchAllFeeds does, unless WithPreserveOrder.)
+func FetchFeed(url string, opts ...Option) ([]BlogPost, error) {
+	return NewFetcher(opts...).FetchFeed(url)
 }
 
-func cleanHTML(input string, maxLength int) string {
-	// first, remove HTML tags
-	tagRegex := regexp.MustCompile("<[^>]*>")
-	cleaned := tagRegex.ReplaceAllString(input, "")
+func (f *Fetcher) FetchFeed(url string) ([]BlogPost, error) {
+	return f.FetchFeedContext(context.Background(), url)
+}
 
-	// & convert HTML entities
-	cleaned = html.UnescapeString(cleaned)
+func (f *Fetcher) FetchFeedContext(ctx context.Context, url string) ([]BlogPost, error) {
+	_, posts, err := f.fetchFeed(ctx, url, f.opts)
+	return posts, err
+}
 
-	// & normalize whitespace
-	wsRegex := regexp.MustCompile(`\s+`)
-	cleaned = wsRegex.ReplaceAllString(cleaned, " ")
+// FetchFeedInfo is FetchFeed that also hands back the feed-level metadata.
+func (f *Fetcher) FetchFeedInfo(ctx context.Context, url string) (FeedInfo, []BlogPost, error) {
+	return f.fetchFeed(ctx, url, f.opts)
+}
 
-	// & truncate to maxLength
-	if len(cleaned) > maxLength {
-		cleaned = cleaned[:maxLength]
+func (f *Fetcher) fetchFeed(ctx context.Context, url string, o *options) (FeedInfo, []BlogPost, error) {
+	return f.fetchWith(ctx, url, o, func(ctx context.Context) ([]byte, error) {
+		return f.fetchBody(ctx, url, o)
+	})
+}
+
+// fetchWith is fetchFeed with the download swapped out, so
+// FetchFeedConditional can bring its own validators
+func (f *Fetcher) fetchWith(ctx context.Context, url string, o *options, get func(context.Context) ([]byte, error)) (FeedInfo, []BlogPost, error) {
+	// waiting for a slot doesn't count against the feed's own timeout
+	if f.slots != nil {
+		select {
+		case f.slots <- struct{}{}:
+			defer func() { <-f.slots }()
+		case <-ctx.Done():
+			return FeedInfo{}, nil, fmt.Errorf("waiting to fetch %s: %w", url, ctx.Err())
+		}
+	}
+
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	body, err := get(ctx)
+	if err != nil {
+		return FeedInfo{}, nil, err
+	}
 
-		if cleaned[len(cleaned)-1] == ' ' || cleaned[len(cleaned)-1] == '.' {
-			cleaned = cleaned[:len(cleaned)-1]
+	// a parse made with someone else's options (InvalidateAndFetch's copy,
+	// say) isn't safe to hand out, so only the Fetcher's own get cached
+	useParsed := f.parsed != nil && o == f.opts
+	var sum [sha256.Size]byte
+	if useParsed {
+		sum = sha256.Sum256(body)
+		if info, posts, ok := f.parsed.get(url, sum); ok {
+			return info, posts, nil
 		}
+	}
 
-		cleaned += "..."
+	info, posts, err := parseFeedPosts(bytes.NewReader(body), o.fetchedFrom(url))
+	if err != nil {
+		info.resolveImages(url)
+		return info, nil, withURL(err, url)
 	}
 
-	return strings.TrimSpace(cleaned)
+	info.resolveImages(url)
+	if o.faviconLookup && info.Link != "" {
+		info.FaviconURL = f.lookupFavicon(ctx, info.Link, o.forTarget(url, info.Link))
+	}
+	tagSource(posts, url)
+	if o.sourceTitles && f.sources != nil {
+		f.fillOriginTitles(ctx, url, posts, o)
+	}
+	if useParsed {
+		f.parsed.set(url, sum, info, posts)
+	}
+	return info, posts, nil
 }
 
-func parseDate(item Item) time.Time {
-	dateCandidates := []string{
-		item.PubDate,
-		item.Date,
-		item.Published,
-		item.Updated,
+func (f *Fetcher) fetchBody(ctx context.Context, url string, o *options) ([]byte, error) {
+	entry, cached := f.cacheGet(url)
+	if o.forceRefresh {
+		entry, cached = CacheEntry{}, false
+	}
+	if cached && o.headProbe && f.unchanged(ctx, url, entry, o) {
+		return entry.Body, nil
 	}
 
-	for _, dateStr := range dateCandidates {
-		if dateStr == "" {
-			continue
-		}
+	d, err := f.download(ctx, url, entry, o)
+	if errors.Is(err, ErrNotModified) && cached {
+		return entry.Body, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		for _, format := range dateFormats {
-			if t, err := time.Parse(format, dateStr); err == nil {
-				return t
-			}
+	// never cache half a feed, the next fetch should get the whole thing
+	if !d.partial {
+		f.cacheSet(url, CacheEntry{
+			ETag:         d.etag,
+			LastModified: d.lastModified,
+			Body:         d.body,
+		})
+	}
+
+	return d.body, nil
+}
+
+// downloaded is one GET's worth of feed, validators & all
+type downloaded struct {
+	body               []byte
+	etag, lastModified string
+	// cut off mid-download & let through by WithLenient
+	partial bool
+}
+
+// download sends entry's validators along & reports a 304 as
+// ErrNotModified, it's up to the caller what to serve instead.
+// the validators from a 304 come back too, servers do rotate them.
+func (f *Fetcher) download(ctx context.Context, url string, entry CacheEntry, o *options) (*downloaded, error) {
+	if o.onTiming != nil {
+		timer := newRequestTimer()
+		ctx = timer.attach(ctx)
+		defer timer.report(url, o.onTiming)
+	}
+
+	req, err := f.newRequest(ctx, http.MethodGet, url, entry, o)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.do(ctx, req, o)
+	if err != nil {
+		return nil, fmt.Errorf("fetching feed %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if o.onResponse != nil {
+		o.onResponse(url, inspectable(resp))
+	}
+
+	d := &downloaded{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		return d, fmt.Errorf("feed %s: %w", url, ErrNotModified)
+	}
+
+	if isCloudflareChallenge(resp) {
+		return nil, fmt.Errorf("feed %s: %w", url, ErrBlockedByCloudflare)
+	}
+
+	// an error page isn't a feed, however well-formed it is. a 410 in
+	// particular is the server telling us to stop asking (ErrFeedGone).
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &FeedHTTPError{URL: url, StatusCode: resp.StatusCode}
+	}
+
+	// no point downloading something we already know we'll throw away
+	if o.maxBodyBytes > 0 && resp.ContentLength > o.maxBodyBytes {
+		return nil, fmt.Errorf("feed %s declares %d bytes: %w", url, resp.ContentLength, ErrFeedTooLarge)
+	}
+
+	var r io.Reader = resp.Body
+	if b := budgetFrom(ctx); b != nil {
+		r = budgetReader{r: r, b: b}
+	}
+
+	d.body, err = readBody(r, o.maxBodyBytes)
+	if isTruncated(err) {
+		if !o.lenient {
+			return nil, fmt.Errorf("reading res from %s: %w: %w", url, ErrTruncatedFeed, err)
 		}
+		log.Printf("warn: %s was cut off mid-download, parsing what arrived", url)
+		d.partial = true
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading res from %s: %w", url, err)
 	}
 
-	log.Printf("warn: Could not parse any date from item %s", item.Title)
-	return time.Now()
+	return d, nil
 }
 
-func getDescription(item Item) string {
-	candidates := []string{
-		item.Description,
-		item.Summary,
-		item.Content,
-		item.Encoded,
+// every request we send goes through here, conditional headers included
+func (f *Fetcher) newRequest(ctx context.Context, method, url string, entry CacheEntry, o *options) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building request for %s: %w", url, err)
 	}
 
-	for _, candidate := range candidates {
-		if candidate != "" {
-			return stripMarkdown(cleanHTML(candidate, 200))
+	for key, values := range o.headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
 		}
 	}
 
-	return "Visit post for details."
+	if entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+
+	return req, nil
+}
+
+// inspectable is resp as WithOnResponse hooks see it: its own copy of the
+// headers & no body, so nothing a hook does can get in the way of parsing
+func inspectable(resp *http.Response) *http.Response {
+	copied := *resp
+	copied.Header = resp.Header.Clone()
+	copied.Trailer = resp.Trailer.Clone()
+	copied.Body = http.NoBody
+	return &copied
 }
 
-func getAuthor(item Item, channelTitle string) string {
-	if item.Author != "" {
-		return item.Author
+func tagSource(posts []BlogPost, url string) {
+	for i := range posts {
+		posts[i].SourceURL = url
+	}
+}
+
+var cloudflareMarkers = [][]byte{
+	[]byte("cf-browser-verification"),
+	[]byte("challenge-platform"),
+	[]byte("cf_chl_"),
+	[]byte("<title>Just a moment...</title>"),
+}
+
+// cloudflare's bot wall answers with a 503 (or 403 for managed challenges)
+// and an HTML page. newer setups flag it in a header, older ones we have to
+// recognize from the page itself.
+func isCloudflareChallenge(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusServiceUnavailable && resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	if resp.Header.Get("cf-mitigated") == "challenge" {
+		return true
 	}
-	if item.Creator != "" {
-		return item.Creator
+	if !strings.EqualFold(resp.Header.Get("Server"), "cloudflare") {
+		return false
 	}
-	return channelTitle
+
+	// the markers are all near the top, no need to read the whole page.
+	// whatever we peek at gets stitched back on for the normal read.
+	page, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body = readCloser{io.MultiReader(bytes.NewReader(page), resp.Body), resp.Body}
+
+	for _, marker := range cloudflareMarkers {
+		if bytes.Contains(page, marker) {
+			return true
+		}
+	}
+
+	return false
 }
 
-func FetchFeed(url string) ([]BlogPost, error) {
-	resp, err := http.Get(url)
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// chunked responses (or liars) don't give us a usable Content-Length, so we
+// still have to count as we go. on a read error, whatever did arrive comes
+// back alongside it.
+func readBody(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
 	if err != nil {
-		return nil, fmt.Errorf("fetching feed %s: %w", url, err)
+		return body, err
 	}
-	defer resp.Body.Close()
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("more than %d bytes: %w", limit, ErrFeedTooLarge)
+	}
+
+	return body, nil
+}
+
+func FetchAllFeeds(feeds []string, opts ...Option) []BlogPost {
+	return NewFetcher(opts...).FetchAllFeeds(feeds)
+}
+
+// FetchAllFeedsStrict is the all-or-nothing FetchAllFeeds: the first feed to
+// fail cancels the rest and its error comes back with no posts at all.
+func FetchAllFeedsStrict(ctx context.Context, feeds []string, opts ...Option) ([]BlogPost, error) {
+	return NewFetcher(opts...).FetchAllFeedsStrict(ctx, feeds)
+}
+
+func (f *Fetcher) FetchAllFeeds(feeds []string) []BlogPost {
+	return f.FetchAllFeedsContext(context.Background(), feeds)
+}
+
+// FetchAllFeedsContext fetches every feed at once, best effort: feeds that
+// fail get logged and skipped. A feed listed twice, even written slightly
+// differently (see normalizeFeedURL), is only fetched once.
+func (f *Fetcher) FetchAllFeedsContext(ctx context.Context, feeds []string) []BlogPost {
+	res, _ := f.fetchAll(ctx, f.requests(feeds), false)
+	capSources(res.groups, f.opts)
+	return mergePosts(flatten(res.groups), f.opts)
+}
 
-	body, err := io.ReadAll(resp.Body)
+func (f *Fetcher) FetchAllFeedsStrict(ctx context.Context, feeds []string) ([]BlogPost, error) {
+	res, err := f.fetchAll(ctx, f.requests(feeds), true)
 	if err != nil {
-		return nil, fmt.Errorf("reading res from %s: %w", url, err)
+		return nil, err
 	}
+	capSources(res.groups, f.opts)
+	return mergePosts(flatten(res.groups), f.opts), nil
+}
 
-	var feed Feed
-	if err := xml.Unmarshal(body, &feed); err != nil {
-		return nil, fmt.Errorf("parsing feed %s: %w", url, err)
+// FetchAllFeedsGrouped fetches concurrently like FetchAllFeeds but keeps each
+// feed's posts apart: result[i] holds the posts from feeds[i], sorted on
+// their own (& cut to WithMaxPerSource). A feed that failed leaves a nil
+// slice in its spot.
+func (f *Fetcher) FetchAllFeedsGrouped(ctx context.Context, feeds []string) [][]BlogPost {
+	res, _ := f.fetchAll(ctx, f.requests(feeds), false)
+	capSources(res.groups, f.opts)
+	return res.groups
+}
+
+// capSources sorts each feed's posts & keeps its top WithMaxPerSource
+// (its first ones, with WithPreserveOrder)
+func capSources(groups [][]BlogPost, o *options) {
+	for i, group := range groups {
+		if !o.preserveOrder {
+			sortPosts(group, o.sortOrder)
+		}
+		if o.maxPerSource > 0 && len(group) > o.maxPerSource {
+			groups[i] = group[:o.maxPerSource]
+		}
 	}
+}
 
-	var posts []BlogPost
+// FeedRequest is one feed for FetchAllFeedsWith, with its own options
+// layered over the Fetcher's. Anything about fetching & parsing that one
+// feed works (WithHeader, WithTimeout, WithGeneratorQuirks...), & its
+// WithHeader headers go to that feed's host only. What doesn't work here,
+// & has to go on the Fetcher instead:
+//   - WithHTTPClient, WithProxy & WithTransportConfig, the client is built once
+//   - WithCache, WithStore, WithParsedCache & WithSourceTitleLookup, which
+//     need state the Fetcher sets up (WithHeadProbe does work, if the
+//     Fetcher has a cache to probe against)
+//   - WithTotalBudget & WithMaxConcurrentFetches, limits across all feeds
+//   - WithSortOrder, WithPreserveOrder, WithMergeStrategy, WithMaxPerSource,
+//     WithMaxTotalPosts & WithLooseLinkDedup, which are about the merge
+//
+// A feed with options of its own also skips the Fetcher's parsed cache.
+type FeedRequest struct {
+	URL     string
+	Options []Option
+}
 
-	// we need to worry about both RSS *AND* Atom feeds
-	items := feed.Channel.Items
-	if len(items) == 0 {
-		items = feed.Channel.Entries
+func FetchAllFeedsWith(ctx context.Context, feeds []FeedRequest, opts ...Option) []BlogPost {
+	return NewFetcher(opts...).FetchAllFeedsWith(ctx, feeds)
+}
+
+// FetchAllFeedsWith is FetchAllFeedsContext for subscription lists where
+// feeds need different treatment: auth headers, longer timeouts, quirks...
+// Everything still gets merged, deduped & sorted together.
+func (f *Fetcher) FetchAllFeedsWith(ctx context.Context, feeds []FeedRequest) []BlogPost {
+	res, _ := f.fetchAll(ctx, feeds, false)
+	capSources(res.groups, f.opts)
+	return mergePosts(flatten(res.groups), f.opts)
+}
+
+func (f *Fetcher) requests(feeds []string) []FeedRequest {
+	reqs := make([]FeedRequest, len(feeds))
+	for i, url := range feeds {
+		reqs[i] = FeedRequest{URL: url}
 	}
-	if len(items) == 0 {
-		items = feed.Entries
+	return reqs
+}
+
+// optionsFor is the Fetcher's options with a feed's own on top. no extras
+// means the Fetcher's own pointer, which WithParsedCache relies on.
+func (f *Fetcher) optionsFor(opts []Option) *options {
+	if len(opts) == 0 {
+		return f.opts
 	}
 
-	for _, item := range items {
-		post := BlogPost{
-			Title:   item.Title,
-			Link:    item.Link,
-			Date:    parseDate(item),
-			Author:  getAuthor(item, feed.Channel.Title),
-			Summary: getDescription(item),
+	o := *f.opts
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &o
+}
+
+// fetchOrder normalizes every feed's URL & works out which feeds are the
+// same one twice: first[i] is the feed whose fetch feeds[i] gets, i itself
+// unless an earlier one has the same URL. feeds with options of their own
+// always get a fetch of their own, the options might be why it's listed twice.
+func fetchOrder(feeds []FeedRequest) (urls []string, first []int) {
+	urls = make([]string, len(feeds))
+	first = make([]int, len(feeds))
+	seen := make(map[string]int, len(feeds))
+	for i, feed := range feeds {
+		urls[i], first[i] = normalizeFeedURL(feed.URL), i
+		if len(feed.Options) > 0 {
+			continue
+		}
+		if j, ok := seen[urls[i]]; ok {
+			first[i] = j
+			continue
 		}
-		posts = append(posts, post)
+		seen[urls[i]] = i
 	}
+	return urls, first
+}
 
-	return posts, nil
+// fetchResults has one slot per feed, in input order
+type fetchResults struct {
+	groups [][]BlogPost
+	// errs[i] is why feeds[i] failed, nil if it didn't
+	errs []error
+	// skipped are the feeds WithTotalBudget cut off
+	skipped []string
 }
 
-func FetchAllFeeds(feeds []string) []BlogPost {
+// fetchAll fetches every feed at once. with failFast the first error
+// cancels the rest & comes back on its own.
+func (f *Fetcher) fetchAll(parent context.Context, feeds []FeedRequest, failFast bool) (fetchResults, error) {
+	budgeted, stop := withBudget(parent, f.opts.budget)
+	defer stop()
+	ctx, cancel := context.WithCancel(budgeted)
+	defer cancel()
+
 	var (
-		wg    sync.WaitGroup
-		mu    sync.Mutex
-		posts []BlogPost
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		groups   = make([][]BlogPost, len(feeds))
+		errs     = make([]error, len(feeds))
+		skipped  = make([]bool, len(feeds))
+		firstErr error
+
+		// separate lock so a slow callback doesn't hold up the results
+		doneMu sync.Mutex
 	)
 
-	for _, feedURL := range feeds {
+	urls, first := fetchOrder(feeds)
+	for i, feed := range feeds {
+		if first[i] != i {
+			continue
+		}
 		wg.Add(1)
-		go func(url string) {
+		go func(i int, url string, o *options) {
 			defer wg.Done()
 
-			feedPosts, err := FetchFeed(url)
-			if err != nil {
-				log.Printf("err fetching %s: %v", url, err)
-				return
+			_, feedPosts, err := f.fetchFeed(ctx, url, o)
+			if o.onFeedDone != nil {
+				doneMu.Lock()
+				o.onFeedDone(url, len(feedPosts), err)
+				doneMu.Unlock()
 			}
 
 			mu.Lock()
-			posts = append(posts, feedPosts...)
-			mu.Unlock()
-		}(feedURL)
+			defer mu.Unlock()
+
+			if err != nil && overBudget(parent, budgeted, err) {
+				skipped[i] = true
+			}
+			if err != nil {
+				errs[i] = err
+				if !failFast {
+					log.Printf("err fetching %s: %v", url, err)
+				} else if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			groups[i] = feedPosts
+		}(i, urls[i], f.optionsFor(feed.Options))
 	}
 
 	wg.Wait()
 
-	sort.Slice(posts, func(i, j int) bool {
-		return posts[i].Date.After(posts[j].Date)
-	})
+	// a feed listed twice gets its posts twice, merging dedups them anyway
+	for i, j := range first {
+		if j != i {
+			groups[i] = append([]BlogPost(nil), groups[j]...)
+			errs[i], skipped[i] = errs[j], skipped[j]
+		}
+	}
+
+	res := fetchResults{groups: groups, errs: errs}
+	for i, skip := range skipped {
+		if skip {
+			res.skipped = append(res.skipped, feeds[i].URL)
+		}
+	}
+
+	if firstErr != nil {
+		return fetchResults{skipped: res.skipped}, firstErr
+	}
+
+	return res, nil
+}
+
+func flatten(groups [][]BlogPost) []BlogPost {
+	var posts []BlogPost
+	for _, group := range groups {
+		posts = append(posts, group...)
+	}
+	return posts
+}
+
+// mergePosts is everything that happens once posts from every feed are in one pile
+func mergePosts(posts []BlogPost, o *options) []BlogPost {
+	if !o.preserveOrder {
+		sortPosts(posts, o.sortOrder)
+	}
+
+	// sorted first so the newest copy of a duplicate wins
+	posts = dedupPosts(posts, o)
+	// interleaving goes by date windows, which needs the sort
+	if o.mergeStrategy == MergeInterleave && !o.preserveOrder {
+		posts = interleave(posts, o.sortOrder)
+	}
+
+	// copy out the top N so the rest of the backing array can be collected
+	if o.maxTotalPosts > 0 && len(posts) > o.maxTotalPosts {
+		posts = append([]BlogPost(nil), posts[:o.maxTotalPosts]...)
+	}
 
 	return posts
 }
@@ -0,0 +1,267 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+func postsFeed(title string, n int) string {
+	body := fmt.Sprintf(`<rss version="2.0"><channel><title>%s</title>`, title)
+	for i := 1; i <= n; i++ {
+		body += fmt.Sprintf(`<item><title>%s post %d is here</title><link>https://%s.example.com/%d</link><pubDate>Mon, 0%d Jan 2024 10:00:00 GMT</pubDate></item>`, title, i, title, i, i)
+	}
+	return body + `</channel></rss>`
+}
+
+func TestFetchAllFeedsWith(t *testing.T) {
+	private := newHeaderServer(postsFeed("private", 2))
+	defer private.Close()
+	public := newHeaderServer(postsFeed("public", 2))
+	defer public.Close()
+
+	posts := FetchAllFeedsWith(context.Background(), []FeedRequest{
+		{URL: private.URL, Options: []Option{WithHeader("Authorization", "Bearer secret"), WithMaxTitleLength(8)}},
+		{URL: public.URL},
+	})
+	if len(posts) != 4 {
+		t.Fatalf("got %d posts, want 4", len(posts))
+	}
+
+	tests := []struct {
+		name  string
+		srv   *headerServer
+		auth  string
+		title string
+	}{
+		{"private", private, "Bearer secret", "private..."},
+		{"public", public, "", "public post 2 is here"},
+	}
+	for _, tt := range tests {
+		if got := tt.srv.seen(); len(got) != 1 || got[0] != tt.auth {
+			t.Errorf("%s: got Authorization %q, want %q", tt.name, got, tt.auth)
+		}
+
+		found := false
+		for _, post := range posts {
+			if post.SourceURL == tt.srv.URL {
+				found = true
+				if post.Title != tt.title {
+					t.Errorf("%s: newest title %q, want %q", tt.name, post.Title, tt.title)
+				}
+				break
+			}
+		}
+		if !found {
+			t.Errorf("%s: no posts", tt.name)
+		}
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	big := []byte(postsFeed("big", 9))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		switch r.URL.Path {
+		case "/declared":
+			// claims a lot more than it'll ever send, so reading the body
+			// at all would sit here until the client gives up
+			w.Header().Set("Content-Length", "100000000")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		case "/chunked":
+			// no Content-Length, only reading it finds out how big it is
+			for i := 0; i < len(big); i += 100 {
+				w.Write(big[i:min(i+100, len(big))])
+				w.(http.Flusher).Flush()
+			}
+		default:
+			w.Write(big)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		path   string
+		limit  int64
+		tooBig bool
+	}{
+		{"declared too large", "/declared", 1 << 20, true},
+		{"chunked too large", "/chunked", 512, true},
+		{"chunked under the limit", "/chunked", 1 << 20, false},
+		{"plain under the limit", "/plain", int64(len(big)), false},
+		{"no limit", "/chunked", 0, false},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		_, err := FetchFeed(srv.URL+tt.path, WithMaxBodyBytes(tt.limit), WithTimeout(5*time.Second))
+		if got := errors.Is(err, ErrFeedTooLarge); got != tt.tooBig {
+			t.Errorf("%s: err %v, want ErrFeedTooLarge: %v", tt.name, err, tt.tooBig)
+		}
+		if !tt.tooBig && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%s: took %v, the body was read", tt.name, elapsed)
+		}
+	}
+}
+
+func TestEmptyBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty", ""},
+		{"whitespace", " \r\n\t\n"},
+		{"just a BOM", "\xef\xbb\xbf\n"},
+	}
+	for _, tt := range tests {
+		srv := feedtest.NewServer([]byte(tt.body))
+		_, err := FetchFeed(srv.URL)
+		if !errors.Is(err, ErrEmptyFeed) {
+			t.Errorf("%s: err %v, want ErrEmptyFeed", tt.name, err)
+		} else if !strings.Contains(err.Error(), srv.URL) {
+			t.Errorf("%s: %q doesn't say which feed", tt.name, err)
+		}
+		srv.Close()
+	}
+}
+
+func TestMaxConcurrentFetchesAcrossCalls(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(w, postsFeed("busy"+strings.ReplaceAll(r.URL.Path, "/", "-"), 1))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		max  int
+	}{
+		{"capped at 1", 1},
+		{"capped at 3", 3},
+		// with no cap all 20 requests could be out at once
+		{"no cap", 0},
+	}
+	for _, tt := range tests {
+		peak.Store(0)
+		f := NewFetcher(WithMaxConcurrentFetches(tt.max))
+
+		var wg sync.WaitGroup
+		for user := 0; user < 4; user++ {
+			var urls []string
+			for i := 0; i < 5; i++ {
+				urls = append(urls, fmt.Sprintf("%s/%d/%d", srv.URL, user, i))
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if posts := f.FetchAllFeedsContext(context.Background(), urls); len(posts) != 5 {
+					t.Errorf("%s: got %d posts, want 5", tt.name, len(posts))
+				}
+			}()
+		}
+		wg.Wait()
+
+		got := int(peak.Load())
+		if tt.max > 0 && got > tt.max {
+			t.Errorf("%s: %d requests in flight at once", tt.name, got)
+		}
+		if tt.max == 0 && got <= 3 {
+			t.Errorf("%s: only %d requests in flight at once, is something else capping them?", tt.name, got)
+		}
+	}
+}
+
+func TestFetchAllFeedsStrict(t *testing.T) {
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, postsFeed("good", 2))
+	}))
+	defer good.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "broken", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	// the slow feed only ever answers if nobody cancels it first
+	var cancelled atomic.Bool
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			cancelled.Store(true)
+		case <-time.After(5 * time.Second):
+			fmt.Fprint(w, postsFeed("slow", 2))
+		}
+	}))
+	defer slow.Close()
+
+	posts, err := FetchAllFeedsStrict(context.Background(), []string{good.URL})
+	if err != nil || len(posts) != 2 {
+		t.Fatalf("all good: %d posts, %v", len(posts), err)
+	}
+
+	var mu sync.Mutex
+	done := make(map[string]error)
+	onDone := WithOnFeedDone(func(url string, _ int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		done[url] = err
+	})
+
+	start := time.Now()
+	posts, err = FetchAllFeedsStrict(context.Background(), []string{good.URL, slow.URL, failing.URL}, onDone)
+	if err == nil || !strings.Contains(err.Error(), failing.URL) {
+		t.Fatalf("got error %v, want the failing feed's", err)
+	}
+	if posts != nil {
+		t.Errorf("got %d posts alongside the error", len(posts))
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("took %v, the slow feed wasn't cancelled", elapsed)
+	}
+
+	// every fetch has wrapped up by the time it returns: the slow one was
+	// cancelled rather than left running, & nothing reports in afterwards
+	mu.Lock()
+	if len(done) != 3 {
+		t.Errorf("%d of 3 feeds finished before returning", len(done))
+	}
+	if !errors.Is(done[slow.URL], context.Canceled) {
+		t.Errorf("slow feed finished with %v, want it cancelled", done[slow.URL])
+	}
+	mu.Unlock()
+
+	// or the caller gives up first
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := FetchAllFeedsStrict(ctx, []string{good.URL, slow.URL}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("caller's timeout: got %v", err)
+	}
+
+	// Close waits for the handlers, so by now the slow one has seen it
+	slow.Close()
+	if !cancelled.Load() {
+		t.Error("slow feed's request never saw the cancellation")
+	}
+}
@@ -0,0 +1,38 @@
+package feed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WithFingerprint fills in FeedInfo.Fingerprint.
+func WithFingerprint() Option {
+	return func(o *options) {
+		o.fingerprint = true
+	}
+}
+
+// what a post contributes to the fingerprint. only identity & dates, so
+// re-cleaning a summary differently doesn't count as the feed changing.
+// an undated post's dates are just when it was fetched, so they're left out.
+func fingerprintKey(post BlogPost) string {
+	id := post.GUID
+	if id == "" {
+		id = post.Link
+	}
+	if !post.dated {
+		return id
+	}
+	return id + "\x00" + post.PublishedAt.UTC().Format(time.RFC3339Nano) +
+		"\x00" + post.UpdatedAt.UTC().Format(time.RFC3339Nano)
+}
+
+// sorted first so a feed that just reshuffles its items hasn't changed
+func fingerprint(keys []string) string {
+	sort.Strings(keys)
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\n")))
+	return hex.EncodeToString(sum[:])
+}
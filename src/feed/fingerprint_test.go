@@ -0,0 +1,58 @@
+package feed
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const fingerprintBase = `<rss version="2.0"><channel><title>Blog</title>
+<item><title>One</title><guid>a</guid><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate><description>first</description></item>
+<item><title>Two</title><guid>b</guid><description>no date</description></item>
+</channel></rss>`
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		name    string
+		feed    string
+		changed bool
+	}{
+		{"same feed", fingerprintBase, false},
+		{"new summary", strings.Replace(fingerprintBase, "first", "first, edited", 1), false},
+		{"new title", strings.Replace(fingerprintBase, "<title>One", "<title>Uno", 1), false},
+		{"reordered", `<rss version="2.0"><channel><title>Blog</title>
+<item><title>Two</title><guid>b</guid><description>no date</description></item>
+<item><title>One</title><guid>a</guid><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate><description>first</description></item>
+</channel></rss>`, false},
+		{"new date", strings.Replace(fingerprintBase, "10:00:00", "11:00:00", 1), true},
+		{"new guid", strings.Replace(fingerprintBase, "<guid>b</guid>", "<guid>c</guid>", 1), true},
+		{"new item", strings.Replace(fingerprintBase, "</channel>", "<item><title>Three</title><guid>d</guid></item></channel>", 1), true},
+		{"item gone", strings.Replace(fingerprintBase, `<item><title>Two</title><guid>b</guid><description>no date</description></item>`, "", 1), true},
+	}
+
+	// every parse gets a later clock, which undated posts mustn't pick up
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	parse := func(body string) string {
+		now = now.Add(time.Minute)
+		clock := now
+		info, _, err := ParseFeedInfo(strings.NewReader(body), WithFingerprint(), WithClock(func() time.Time { return clock }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Fingerprint
+	}
+
+	base := parse(fingerprintBase)
+	if base == "" {
+		t.Fatal("no fingerprint")
+	}
+	for _, tt := range tests {
+		if changed := parse(tt.feed) != base; changed != tt.changed {
+			t.Errorf("%s: changed = %v, want %v", tt.name, changed, tt.changed)
+		}
+	}
+
+	if info, _, _ := ParseFeedInfo(strings.NewReader(fingerprintBase)); info.Fingerprint != "" {
+		t.Error("fingerprint filled in without WithFingerprint")
+	}
+}
@@ -0,0 +1,49 @@
+package feed
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	geoRSSNS = "http://www.georss.org/georss"
+	w3cGeoNS = "http://www.w3.org/2003/01/geo/wgs84_pos#"
+)
+
+// W3C basic geo's <geo:Point>, which some feeds wrap lat & long in
+type GeoPoint struct {
+	Lat  string `xml:"http://www.w3.org/2003/01/geo/wgs84_pos# lat"`
+	Long string `xml:"http://www.w3.org/2003/01/geo/wgs84_pos# long"`
+}
+
+// Location is where a post is about, in WGS84 degrees.
+type Location struct {
+	Lat  float64
+	Long float64
+}
+
+// getLocation reads GeoRSS-Simple's <georss:point>lat long</georss:point>,
+// then W3C geo. nil when there's neither, or the numbers are nonsense.
+func getLocation(item Item) *Location {
+	if fields := strings.Fields(strings.ReplaceAll(item.GeoRSSPoint, ",", " ")); len(fields) == 2 {
+		if loc := parseLocation(fields[0], fields[1]); loc != nil {
+			return loc
+		}
+	}
+	if loc := parseLocation(item.GeoLat, item.GeoLong); loc != nil {
+		return loc
+	}
+	return parseLocation(item.GeoPoint.Lat, item.GeoPoint.Long)
+}
+
+func parseLocation(lat, long string) *Location {
+	la, err := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+	if err != nil || la < -90 || la > 90 {
+		return nil
+	}
+	lo, err := strconv.ParseFloat(strings.TrimSpace(long), 64)
+	if err != nil || lo < -180 || lo > 180 {
+		return nil
+	}
+	return &Location{Lat: la, Long: lo}
+}
@@ -0,0 +1,39 @@
+package feed
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLocation(t *testing.T) {
+	const namespaces = `xmlns:georss="http://www.georss.org/georss" xmlns:geo="http://www.w3.org/2003/01/geo/wgs84_pos#"`
+
+	tests := []struct {
+		name string
+		item string
+		want *Location
+	}{
+		{"georss point", `<georss:point>43.0731 -89.4012</georss:point>`, &Location{43.0731, -89.4012}},
+		{"georss point with a comma", `<georss:point> 43.0731, -89.4012 </georss:point>`, &Location{43.0731, -89.4012}},
+		{"w3c lat & long", `<geo:lat>-33.8688</geo:lat><geo:long>151.2093</geo:long>`, &Location{-33.8688, 151.2093}},
+		{"w3c point", `<geo:Point><geo:lat>51.5072</geo:lat><geo:long>-0.1276</geo:long></geo:Point>`, &Location{51.5072, -0.1276}},
+		// a broken point doesn't hide good w3c coordinates
+		{"bad georss, good w3c", `<georss:point>somewhere</georss:point><geo:lat>10</geo:lat><geo:long>20</geo:long>`, &Location{10, 20}},
+		{"out of range", `<georss:point>91 0</georss:point>`, nil},
+		{"missing long", `<geo:lat>10</geo:lat>`, nil},
+		{"wrong namespace", `<point xmlns="http://example.com/geo">43 -89</point>`, nil},
+		{"none", ``, nil},
+	}
+	for _, tt := range tests {
+		doc := `<rss version="2.0" ` + namespaces + `><channel><title>Map</title><link>https://map.example.com/</link>
+<item><title>Here</title><link>https://map.example.com/1</link>` + tt.item + `</item></channel></rss>`
+		posts, err := ParseFeed(strings.NewReader(doc))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := posts[0].Location
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
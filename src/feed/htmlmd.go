@@ -0,0 +1,257 @@
+package feed
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// the characters that would turn summary text into accidental markdown
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`,
+)
+
+// what would end a link's (destination) early
+var linkEscaper = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E")
+
+// mdConverter writes markdown for a stream of HTML tokens, until limit
+// visible characters are out. then it cuts like truncate does & closes
+// whatever is still open so the markdown stays well-formed.
+type mdConverter struct {
+	b              strings.Builder
+	o              *options
+	base           string
+	limit, visible int
+	done           bool
+	pendingSpace   bool
+	skipDepth      int // inside <script> / <style>
+	codeDepth      int
+	open           []openTag
+}
+
+type openTag struct {
+	name  string
+	close string
+}
+
+var blockTags = map[string]bool{
+	"p": true, "div": true, "blockquote": true, "pre": true, "ul": true, "ol": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "table": true, "tr": true,
+}
+
+// htmlToMarkdown converts links, bold, italics, code & lists, and drops every
+// other tag. the bool is false when the HTML is too broken to make sense
+// of, in which case the caller falls back to plain text. a limit of 0 or
+// less is no limit, same as truncate.
+func htmlToMarkdown(input, base string, limit int, o *options) (string, bool) {
+	if limit <= 0 {
+		limit = math.MaxInt
+	}
+	c := &mdConverter{o: o, base: base, limit: limit}
+	if o.strictTruncation {
+		c.limit -= utf8.RuneCountInString(o.ellipsis)
+	}
+
+	input = stripControl(dropControlRefs(input))
+	d := xml.NewDecoder(strings.NewReader(input))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	for !c.done {
+		tok, err := d.Token()
+		if err != nil {
+			if !endOfHTML(d, err, input) {
+				return "", false
+			}
+			break
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			c.start(tok)
+		case xml.EndElement:
+			c.end(strings.ToLower(tok.Name.Local))
+		case xml.CharData:
+			if c.skipDepth == 0 {
+				c.text(string(tok))
+			}
+		}
+	}
+
+	// whatever the HTML left open still needs closing syntax
+	for i := len(c.open) - 1; i >= 0; i-- {
+		c.closeTag(c.open[i])
+	}
+
+	md := c.b.String()
+	md = mdTrailingSpace.ReplaceAllString(md, "\n")
+	md = mdLeadingSpace.ReplaceAllString(md, "\n")
+	md = newlinesRegex.ReplaceAllString(md, "\n\n")
+	return strings.TrimSpace(md), true
+}
+
+// endOfHTML is whether err is just the end of input, including running
+// out with tags still open ("<p><em>cut off"), which the converters close
+// themselves anyway
+func endOfHTML(d *xml.Decoder, err error, input string) bool {
+	return errors.Is(err, io.EOF) || d.InputOffset() == int64(len(input))
+}
+
+func (c *mdConverter) start(tok xml.StartElement) {
+	name := strings.ToLower(tok.Name.Local)
+	switch {
+	case name == "script" || name == "style":
+		c.skipDepth++
+	case c.skipDepth > 0:
+	case name == "a":
+		href := ""
+		for _, attr := range tok.Attr {
+			if strings.EqualFold(attr.Name.Local, "href") {
+				href = resolveURL(attr.Value, c.base)
+			}
+		}
+		lower := strings.ToLower(href)
+		if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+			// no syntax, just the text
+			c.open = append(c.open, openTag{name: name})
+			return
+		}
+		c.syntax("[")
+		c.open = append(c.open, openTag{name: name, close: "](" + linkEscaper.Replace(href) + ")"})
+	case name == "strong" || name == "b":
+		c.syntax("**")
+		c.open = append(c.open, openTag{name: name, close: "**"})
+	case name == "em" || name == "i":
+		c.syntax("_")
+		c.open = append(c.open, openTag{name: name, close: "_"})
+	case name == "code":
+		c.syntax("`")
+		c.codeDepth++
+		c.open = append(c.open, openTag{name: name, close: "`"})
+	case name == "li":
+		c.linebreak(1)
+		c.b.WriteString("- ")
+	case name == "br":
+		c.linebreak(1)
+	case blockTags[name]:
+		c.linebreak(2)
+	}
+}
+
+func (c *mdConverter) end(name string) {
+	switch {
+	case name == "script" || name == "style":
+		if c.skipDepth > 0 {
+			c.skipDepth--
+		}
+		return
+	case c.skipDepth > 0:
+		return
+	case blockTags[name] || name == "li":
+		c.linebreak(1)
+		return
+	}
+
+	// sloppy HTML closes things out of order, close everything down to the match
+	for i := len(c.open) - 1; i >= 0; i-- {
+		if c.open[i].name != name {
+			continue
+		}
+		for j := len(c.open) - 1; j >= i; j-- {
+			c.closeTag(c.open[j])
+		}
+		c.open = c.open[:i]
+		return
+	}
+}
+
+func (c *mdConverter) closeTag(tag openTag) {
+	c.b.WriteString(tag.close)
+	if tag.name == "code" && c.codeDepth > 0 {
+		c.codeDepth--
+	}
+}
+
+// opening syntax goes after any space we owe, closing syntax hugs the text
+func (c *mdConverter) syntax(s string) {
+	c.flushSpace()
+	c.b.WriteString(s)
+}
+
+// linebreak makes sure the output ends in at least n newlines, so nested
+// blocks don't pile up blank lines (& the start of the output gets none)
+func (c *mdConverter) linebreak(n int) {
+	c.pendingSpace = false
+	if c.b.Len() == 0 {
+		return
+	}
+
+	out := c.b.String()
+	have := len(out) - len(strings.TrimRight(out, "\n"))
+	for ; have < n; have++ {
+		c.b.WriteByte('\n')
+	}
+}
+
+func (c *mdConverter) flushSpace() {
+	if c.pendingSpace && c.b.Len() > 0 {
+		c.b.WriteByte(' ')
+	}
+	c.pendingSpace = false
+}
+
+func (c *mdConverter) text(s string) {
+	s = wsRegex.ReplaceAllString(c.o.typography(s), " ")
+	if s == "" {
+		return
+	}
+	if strings.HasPrefix(s, " ") {
+		c.pendingSpace = true
+	}
+	trailing := strings.HasSuffix(s, " ")
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return
+	}
+
+	runes := []rune(s)
+	remaining := c.limit - c.visible
+	if len(runes) <= remaining {
+		c.flushSpace()
+		c.write(s)
+		c.visible += len(runes)
+		c.pendingSpace = trailing
+		return
+	}
+
+	// over the limit: same word-boundary cut as truncate, then close up shop
+	cut := ""
+	if remaining > 0 {
+		cut = string(runes[:remaining])
+		if i := strings.LastIndexByte(cut, ' '); i > len(cut)/2 {
+			cut = cut[:i]
+		}
+	}
+	cut = strings.TrimRight(cut, " .,;:")
+	if cut != "" {
+		c.flushSpace()
+		c.write(cut)
+	}
+	c.b.WriteString(c.o.ellipsis)
+	for i := len(c.open) - 1; i >= 0; i-- {
+		c.closeTag(c.open[i])
+	}
+	c.open = nil
+	c.done = true
+}
+
+func (c *mdConverter) write(s string) {
+	if c.codeDepth == 0 {
+		s = markdownEscaper.Replace(s)
+	}
+	c.b.WriteString(s)
+}
@@ -0,0 +1,70 @@
+package feed
+
+import "testing"
+
+func TestMarkdownSummaries(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		want        string
+	}{
+		{"link", `Read <a href="https://example.com/more">the rest</a> now`, "Read [the rest](https://example.com/more) now"},
+		{"relative link", `See <a href="/about">about</a>`, "See [about](https://example.com/about)"},
+		{"link with spaces & parens", `See <a href="https://example.com/wiki/Go (language)/a b">the wiki</a>`, "See [the wiki](https://example.com/wiki/Go%20%28language%29/a%20b)"},
+		{"non-web link is just text", `Mail <a href="mailto:jane@example.com">Jane</a>`, "Mail Jane"},
+		{"bold", `This is <strong>important</strong> & <b>loud</b>`, "This is **important** & **loud**"},
+		{"italic", `An <em>emphasized</em> and <i>slanted</i> word`, "An _emphasized_ and _slanted_ word"},
+		{"code", `Run <code>go test ./...</code> first`, "Run `go test ./...` first"},
+		{"list", `<p>Changes:</p><ul><li>faster</li><li>smaller</li></ul>`, "Changes:\n\n- faster\n- smaller"},
+		{"paragraphs", `<p>One.</p><p>Two.</p>`, "One.\n\nTwo."},
+		{"text that looks like markdown", `2 * 3 = [six] with some_snake_case`, `2 \* 3 = \[six\] with some\_snake\_case`},
+		{"scripts dropped", `Hello<script>alert("hi")</script> there`, "Hello there"},
+	}
+	for _, tt := range tests {
+		item := `<item><title>Post</title><link>https://example.com/post</link><description><![CDATA[` + tt.description + `]]></description></item>`
+		post := parseItemXML(t, item, WithMarkdownSummaries(), WithSummaryLength(500))
+		if post.Summary != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, post.Summary, tt.want)
+		}
+	}
+}
+
+func TestMarkdownSummaryLength(t *testing.T) {
+	const description = `<p>A <strong>bold start</strong> to a <a href="https://example.com/x">long sentence that goes on</a> and on</p>`
+	tests := []struct {
+		name  string
+		limit int
+		want  string
+	}{
+		// syntax doesn't count, & whatever is open gets closed
+		{"cut inside a link", 25, "A **bold start** to a [long sente...](https://example.com/x)"},
+		{"cut inside bold", 4, "A **bol...**"},
+		{"fits", 100, "A **bold start** to a [long sentence that goes on](https://example.com/x) and on"},
+	}
+	for _, tt := range tests {
+		item := `<item><title>Post</title><link>https://example.com/post</link><description><![CDATA[` + description + `]]></description></item>`
+		post := parseItemXML(t, item, WithMarkdownSummaries(), WithSummaryLength(tt.limit))
+		if post.Summary != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, post.Summary, tt.want)
+		}
+	}
+}
+
+func TestMarkdownSummaryUnclosed(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		want        string
+	}{
+		{"open at the end", `<p>Some <em>dangling`, "Some _dangling_"},
+		{"open link at the end", `<p>See <a href="https://example.com/x">this`, "See [this](https://example.com/x)"},
+		{"unclosed paragraphs", `<p>One<p>Two`, "One\n\nTwo"},
+	}
+	for _, tt := range tests {
+		item := `<item><title>Post</title><link>https://example.com/post</link><description><![CDATA[` + tt.description + `]]></description></item>`
+		post := parseItemXML(t, item, WithMarkdownSummaries())
+		if post.Summary != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, post.Summary, tt.want)
+		}
+	}
+}
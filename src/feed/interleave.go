@@ -0,0 +1,82 @@
+package feed
+
+import "time"
+
+// MergeStrategy decides how posts from different feeds are put in one list.
+type MergeStrategy int
+
+const (
+	// MergeByDate is a plain sort on the SortOrder timestamp (the default)
+	MergeByDate MergeStrategy = iota
+	// MergeInterleave still goes newest first, but within each week it takes
+	// turns between feeds, so one that posts ten times a day can't push
+	// everyone else off the top of the page
+	MergeInterleave
+)
+
+// how far apart two posts can be and still get shuffled for fairness
+const interleaveWindow = 7 * 24 * time.Hour
+
+// WithMergeStrategy picks how FetchAllFeeds & friends combine feeds.
+func WithMergeStrategy(strategy MergeStrategy) Option {
+	return func(o *options) {
+		o.mergeStrategy = strategy
+	}
+}
+
+func (order SortOrder) timeOf(post BlogPost) time.Time {
+	switch order {
+	case SortByUpdated:
+		return post.UpdatedAt
+	case SortByActivity:
+		return lastActive(post)
+	}
+	return post.PublishedAt
+}
+
+// interleave round-robins sorted posts between their feeds, one window at a
+// time. feeds take turns in the order of their newest post in the window,
+// and each feed's own posts stay in order.
+func interleave(sorted []BlogPost, order SortOrder) []BlogPost {
+	out := make([]BlogPost, 0, len(sorted))
+
+	for start := 0; start < len(sorted); {
+		// posts are newest first, so the window runs back from the first one
+		cutoff := order.timeOf(sorted[start]).Add(-interleaveWindow)
+		end := start + 1
+		for end < len(sorted) && !order.timeOf(sorted[end]).Before(cutoff) {
+			end++
+		}
+
+		var sources []string
+		queues := map[string][]BlogPost{}
+		for _, post := range sorted[start:end] {
+			key := sourceKey(post)
+			if _, ok := queues[key]; !ok {
+				sources = append(sources, key)
+			}
+			queues[key] = append(queues[key], post)
+		}
+
+		for len(out) < end {
+			for _, key := range sources {
+				if queue := queues[key]; len(queue) > 0 {
+					out = append(out, queue[0])
+					queues[key] = queue[1:]
+				}
+			}
+		}
+
+		start = end
+	}
+
+	return out
+}
+
+// parsed posts don't always know their URL, the title has to do then
+func sourceKey(post BlogPost) string {
+	if post.SourceURL != "" {
+		return post.SourceURL
+	}
+	return post.SourceTitle
+}
@@ -0,0 +1,68 @@
+package feed
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+// hourlyFeed is n posts an hour apart, the newest at newest
+func hourlyFeed(name string, n int, newest time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<rss version="2.0"><channel><title>%s</title>`, name)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<item><title>%s %d</title><link>https://%s.example.com/%d</link><pubDate>%s</pubDate></item>`,
+			name, i, name, i, newest.Add(-time.Duration(i)*time.Hour).Format(time.RFC1123Z))
+	}
+	b.WriteString(`</channel></rss>`)
+	return []byte(b.String())
+}
+
+func TestMergeInterleave(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	servers := []*feedtest.Server{
+		feedtest.NewServer(hourlyFeed("prolific", 100, now)),
+		// a few hours behind the prolific one, which would bury them
+		feedtest.NewServer(hourlyFeed("quiet", 2, now.Add(-5*time.Hour))),
+		feedtest.NewServer(hourlyFeed("rare", 2, now.Add(-30*time.Hour))),
+	}
+	var urls []string
+	for _, srv := range servers {
+		defer srv.Close()
+		urls = append(urls, srv.URL)
+	}
+
+	sources := func(posts []BlogPost, n int) []string {
+		var names []string
+		for _, post := range posts[:n] {
+			names = append(names, post.SourceTitle)
+		}
+		return names
+	}
+
+	byDate := FetchAllFeeds(urls)
+	if got := sources(byDate, 5); strings.Join(got, ",") != "prolific,prolific,prolific,prolific,prolific" {
+		t.Errorf("by date, top 5 from %v", got)
+	}
+
+	fair := FetchAllFeeds(urls, WithMergeStrategy(MergeInterleave))
+	if len(fair) != 104 {
+		t.Fatalf("interleaved %d posts, want all 104", len(fair))
+	}
+	want := "prolific,quiet,rare,prolific,quiet,rare,prolific"
+	if got := sources(fair, 7); strings.Join(got, ",") != want {
+		t.Errorf("interleaved, top 7 from %v, want %s", got, want)
+	}
+
+	// each feed's own posts are still newest first
+	last := map[string]time.Time{}
+	for _, post := range fair {
+		if prev, ok := last[post.SourceTitle]; ok && post.PublishedAt.After(prev) {
+			t.Errorf("%s: %q is out of order", post.SourceTitle, post.Title)
+		}
+		last[post.SourceTitle] = post.PublishedAt
+	}
+}
@@ -0,0 +1,188 @@
+package feed
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// CanonicalizeLink validates a post link and rewrites it into a stable form
+// so the same article linked slightly differently compares equal.
+// Only absolute http/https links are accepted, plus protocol-relative
+// ones ("//example.com/post"), which are taken to be https.
+func CanonicalizeLink(raw string) (string, error) {
+	u, err := url.Parse(withScheme(raw, "https"))
+	if err != nil {
+		return "", fmt.Errorf("parsing link %q: %w", raw, err)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("link %q: unsupported scheme %q", raw, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("link %q: missing host", raw)
+	}
+
+	// lowercase the host & drop ports that are implied by the scheme
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	// Encode sorts by key, which is exactly what we want
+	if u.RawQuery != "" {
+		u.RawQuery = u.Query().Encode()
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	return u.String(), nil
+}
+
+// normalizeFeedURL is the form of a feed URL that actually gets fetched:
+// scheme & host lowercased, default port & fragment dropped. unlike
+// CanonicalizeLink the path & query are left as is, a feed's server might
+// care, & so the usual feed URL comes out exactly as it went in. anything
+// unparseable is left for the fetch itself to complain about.
+func normalizeFeedURL(raw string) string {
+	trimmed := strings.TrimSpace(raw)
+	u, err := url.Parse(trimmed)
+	if err != nil || u.Host == "" {
+		return trimmed
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	return u.String()
+}
+
+// DedupPosts keeps the first post for each key, in order. Posts whose key is
+// "" aren't considered duplicates of anything and are always kept.
+// posts itself is left alone.
+func DedupPosts(posts []BlogPost, key func(BlogPost) string) []BlogPost {
+	seen := make(map[string]bool, len(posts))
+	out := make([]BlogPost, 0, len(posts))
+
+	for _, post := range posts {
+		k := key(post)
+		if k == "" {
+			out = append(out, post)
+			continue
+		}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		out = append(out, post)
+	}
+
+	return out
+}
+
+// only posts with a valid link take part in dedup, everything else is kept
+func dedupPosts(posts []BlogPost, o *options) []BlogPost {
+	if o.looseDedup {
+		return DedupPosts(posts, LooseLinkKey)
+	}
+	return DedupPosts(posts, linkKey)
+}
+
+// a canonical URL from the content beats the feed's own link, that's how
+// the same article on two sites ends up with the same key
+func linkKey(post BlogPost) string {
+	link := post.Link
+	if post.CanonicalURL != "" {
+		link = post.CanonicalURL
+	}
+
+	key, err := CanonicalizeLink(link)
+	if err != nil {
+		return ""
+	}
+	return key
+}
+
+// withScheme gives a protocol-relative URL ("//cdn.example.com/a.jpg") the
+// scheme it would have inherited from the page. anything else comes back
+// trimmed but otherwise untouched.
+func withScheme(ref, scheme string) string {
+	ref = strings.TrimSpace(ref)
+	if !strings.HasPrefix(ref, "//") {
+		return ref
+	}
+	return scheme + ":" + ref
+}
+
+// feedScheme is the scheme protocol-relative URLs in a feed inherit: the
+// feed's own, going by the URL it was fetched from, else its self link or
+// xml:base, else https
+func feedScheme(urls ...string) string {
+	for _, raw := range urls {
+		u, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil {
+			continue
+		}
+		if scheme := strings.ToLower(u.Scheme); scheme == "http" || scheme == "https" {
+			return scheme
+		}
+	}
+	return "https"
+}
+
+// fetchedFrom is o for parsing a body fetched from feedURL, so
+// protocol-relative URLs in it pick up that scheme
+func (o *options) fetchedFrom(feedURL string) *options {
+	if o.feedURL == feedURL {
+		return o
+	}
+	fetched := *o
+	fetched.feedURL = feedURL
+	return &fetched
+}
+
+// LooseLinkKey is the dedup key WithLooseLinkDedup uses: the canonical link
+// with the scheme forced to https & any leading "www." dropped, so
+// http://www.example.com/a & https://example.com/a count as one post.
+// "" for posts without a valid link.
+func LooseLinkKey(post BlogPost) string {
+	key := linkKey(post)
+	if key == "" {
+		return ""
+	}
+
+	u, err := url.Parse(key)
+	if err != nil {
+		return key
+	}
+	// default ports are already gone, so this can't leave a stray :80
+	u.Scheme = "https"
+	u.Host = strings.TrimPrefix(u.Host, "www.")
+	return u.String()
+}
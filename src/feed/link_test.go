@@ -0,0 +1,219 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+func TestCanonicalizeLink(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "https://example.com/post", want: "https://example.com/post"},
+		{raw: "HTTPS://Example.COM/Post", want: "https://example.com/Post"},
+		{raw: "https://example.com:443/post", want: "https://example.com/post"},
+		{raw: "http://example.com:80/post", want: "http://example.com/post"},
+		{raw: "http://example.com:8080/post", want: "http://example.com:8080/post"},
+		{raw: "https://example.com", want: "https://example.com/"},
+		{raw: "https://example.com/post?b=2&a=1", want: "https://example.com/post?a=1&b=2"},
+		{raw: "https://example.com/post#comments", want: "https://example.com/post"},
+		{raw: "//example.com/post", want: "https://example.com/post"},
+		{raw: "https://[::1]:443/post", want: "https://[::1]/post"},
+		{raw: "ftp://example.com/post", wantErr: true},
+		{raw: "mailto:someone@example.com", wantErr: true},
+		{raw: "/relative/post", wantErr: true},
+		{raw: "https:///no-host", wantErr: true},
+		{raw: "https://exa mple.com/%zz", wantErr: true},
+		{raw: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := CanonicalizeLink(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CanonicalizeLink(%q) err = %v, want error: %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("CanonicalizeLink(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestDedupPosts(t *testing.T) {
+	posts := []BlogPost{
+		{Title: "first", Link: "https://example.com/a?x=1&y=2"},
+		{Title: "same, messier", Link: "HTTPS://EXAMPLE.com:443/a?y=2&x=1#top"},
+		{Title: "other", Link: "https://example.com/b"},
+		{Title: "broken link", Link: "not a link"},
+		{Title: "broken again", Link: "not a link"},
+		{Title: "no link"},
+		{Title: "canonical", Link: "https://mirror.example.net/a-copy", CanonicalURL: "https://example.com/b"},
+	}
+
+	got := dedupPosts(posts, newOptions(nil))
+	want := []string{"first", "other", "broken link", "broken again", "no link"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d posts, want %d: %+v", len(got), len(want), got)
+	}
+	for i, title := range want {
+		if got[i].Title != title {
+			t.Errorf("post %d: got %q, want %q", i, got[i].Title, title)
+		}
+	}
+	if len(posts) != 7 {
+		t.Error("posts was changed")
+	}
+}
+
+func TestDedupPostsKeys(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	posts := []BlogPost{
+		{Title: "Hello World", Link: "https://a.example.com/hello", GUID: "1", Author: "amy", PublishedAt: jan},
+		{Title: "hello world ", Link: "http://www.a.example.com/hello", GUID: "2", Author: "bo", PublishedAt: jan},
+		{Title: "Something else", Link: "https://a.example.com/hello?utm_source=rss", GUID: "1", Author: "amy", PublishedAt: jan},
+		{Title: "Hello World", Link: "https://a.example.com/again", GUID: "3", Author: "amy", PublishedAt: feb},
+	}
+
+	tests := []struct {
+		name string
+		key  func(BlogPost) string
+		want []string
+	}{
+		{"link", linkKey, []string{"1", "2", "1", "3"}},
+		{"loose link", LooseLinkKey, []string{"1", "1", "3"}},
+		{"guid", func(p BlogPost) string { return p.GUID }, []string{"1", "2", "3"}},
+		{"normalized title", func(p BlogPost) string { return strings.ToLower(strings.TrimSpace(p.Title)) }, []string{"1", "1"}},
+		{"author & date", func(p BlogPost) string { return p.Author + "|" + p.PublishedAt.Format(time.RFC3339) }, []string{"1", "2", "3"}},
+		{"empty keys are never duplicates", func(BlogPost) string { return "" }, []string{"1", "2", "1", "3"}},
+	}
+	for _, tt := range tests {
+		var guids []string
+		for _, post := range DedupPosts(posts, tt.key) {
+			guids = append(guids, post.GUID)
+		}
+		if !reflect.DeepEqual(guids, tt.want) {
+			t.Errorf("%s: kept %q, want %q", tt.name, guids, tt.want)
+		}
+	}
+}
+
+func TestLooseLinkKey(t *testing.T) {
+	same := []string{
+		"https://example.com/post",
+		"http://example.com/post",
+		"https://www.example.com/post",
+		"http://WWW.Example.com:80/post#comments",
+		"//www.example.com/post",
+	}
+	want := LooseLinkKey(BlogPost{Link: same[0]})
+	if want == "" {
+		t.Fatal("no key for a plain link")
+	}
+	for _, link := range same[1:] {
+		if got := LooseLinkKey(BlogPost{Link: link}); got != want {
+			t.Errorf("LooseLinkKey(%q) = %q, want %q", link, got, want)
+		}
+	}
+
+	different := []string{
+		"https://example.com/other",
+		"https://blog.example.com/post",
+		"https://wwwexample.com/post",
+		"https://example.com/post?page=2",
+	}
+	for _, link := range different {
+		if got := LooseLinkKey(BlogPost{Link: link}); got == want {
+			t.Errorf("LooseLinkKey(%q) collides with %q", link, same[0])
+		}
+	}
+	if got := LooseLinkKey(BlogPost{Link: "not a link"}); got != "" {
+		t.Errorf("invalid link got key %q", got)
+	}
+}
+
+func TestWithLooseLinkDedup(t *testing.T) {
+	posts := []BlogPost{
+		{Title: "secure", Link: "https://www.example.com/post"},
+		{Title: "plain", Link: "http://example.com/post"},
+	}
+	tests := []struct {
+		name string
+		opts []Option
+		want int
+	}{
+		{"exact links", nil, 2},
+		{"loose links", []Option{WithLooseLinkDedup()}, 1},
+	}
+	for _, tt := range tests {
+		got := dedupPosts(append([]BlogPost(nil), posts...), newOptions(tt.opts))
+		if len(got) != tt.want {
+			t.Errorf("%s: kept %d, want %d", tt.name, len(got), tt.want)
+		}
+		// the kept post's link is left as the feed had it
+		if got[0].Link != posts[0].Link {
+			t.Errorf("%s: link became %q", tt.name, got[0].Link)
+		}
+	}
+}
+
+// every URL in here leaves the scheme up to whoever fetched the feed
+const protocolRelativeFeed = `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/"><channel>
+<title>Relative</title><link>//relative.example.com/</link>%s
+<item><title>Post</title><link>//relative.example.com/post</link>
+<enclosure url="//cdn.relative.example.com/ep.mp3" length="10" type="audio/mpeg"/>
+<media:thumbnail url="//cdn.relative.example.com/thumb.jpg"/></item>
+</channel></rss>`
+
+func TestProtocolRelativeURLs(t *testing.T) {
+	check := func(name string, info FeedInfo, posts []BlogPost, scheme string) {
+		t.Helper()
+		post := posts[0]
+		got := []string{info.Link, post.Link, post.Enclosures[0].URL, post.Image}
+		want := []string{
+			scheme + "://relative.example.com/",
+			scheme + "://relative.example.com/post",
+			scheme + "://cdn.relative.example.com/ep.mp3",
+			scheme + "://cdn.relative.example.com/thumb.jpg",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+
+	info, posts, err := ParseFeedInfo(strings.NewReader(fmt.Sprintf(protocolRelativeFeed, "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("nothing to go on", info, posts, "https")
+
+	selfHTTP := `<atom:link rel="self" href="http://relative.example.com/feed.xml"/>`
+	info, posts, err = ParseFeedInfo(strings.NewReader(fmt.Sprintf(protocolRelativeFeed, selfHTTP)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("self link", info, posts, "http")
+
+	// where it actually came from beats whatever the feed claims about itself
+	selfHTTPS := `<atom:link rel="self" href="https://relative.example.com/feed.xml"/>`
+	srv := feedtest.NewServer([]byte(fmt.Sprintf(protocolRelativeFeed, selfHTTPS)))
+	defer srv.Close()
+	info, posts, err = NewFetcher().FetchFeedInfo(context.Background(), srv.URL+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("fetched over http", info, posts, "http")
+
+	posts, errs := ParseFeeds(map[string][]byte{"http://relative.example.com/feed.xml": []byte(fmt.Sprintf(protocolRelativeFeed, selfHTTPS))})
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	check("ParseFeeds", FeedInfo{Link: "http://relative.example.com/"}, posts, "http")
+}
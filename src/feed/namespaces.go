@@ -0,0 +1,64 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"sort"
+)
+
+// every namespace something in types.go actually reads from
+var handledNamespaces = map[string]bool{
+	"":                         true,
+	atomNS:                     true,
+	rdfNS:                      true,
+	itunesNS:                   true,
+	"http://purl.org/atom/ns#": true, // atom 0.3
+	"http://purl.org/rss/1.0/": true,
+	"http://purl.org/rss/1.0/modules/content/": true,
+	"http://purl.org/dc/elements/1.1/":         true,
+	"http://search.yahoo.com/mrss/":            true,
+	wfwNS:                                      true,
+	podcastNS:                                  true,
+	geoRSSNS:                                   true,
+	w3cGeoNS:                                   true,
+	"http://www.w3.org/XML/1998/namespace":     true,
+	"http://www.w3.org/2000/xmlns/":            true,
+}
+
+// unhandledNamespaces is a second pass over the document, so it only runs
+// with WithNamespaceReport. it maps each namespace we ignore to the element
+// names seen in it.
+func unhandledNamespaces(body []byte) map[string][]string {
+	seen := make(map[string]map[string]bool)
+
+	d := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			// the real parse already said whether the document is ok
+			break
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || handledNamespaces[start.Name.Space] {
+			continue
+		}
+		if seen[start.Name.Space] == nil {
+			seen[start.Name.Space] = make(map[string]bool)
+		}
+		seen[start.Name.Space][start.Name.Local] = true
+	}
+
+	if len(seen) == 0 {
+		return nil
+	}
+
+	report := make(map[string][]string, len(seen))
+	for ns, names := range seen {
+		for name := range names {
+			report[ns] = append(report[ns], name)
+		}
+		sort.Strings(report[ns])
+	}
+	return report
+}
@@ -0,0 +1,73 @@
+package feed
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type opmlDoc struct {
+	Outlines []opmlOutline `xml:"body>outline"`
+}
+
+// readers nest feeds in folders as deep as they like
+type opmlOutline struct {
+	XMLURL   string        `xml:"xmlUrl,attr"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// ParseOPML returns the feed URLs in an OPML subscription list, folders
+// flattened & duplicates dropped, in document order.
+func ParseOPML(r io.Reader) ([]string, error) {
+	var doc opmlDoc
+	d := xml.NewDecoder(r)
+	// exporters aren't any better at XML than feeds are
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	if err := d.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing opml: %w", err)
+	}
+
+	var urls []string
+	seen := make(map[string]bool)
+	var walk func([]opmlOutline)
+	walk = func(outlines []opmlOutline) {
+		for _, outline := range outlines {
+			if url := strings.TrimSpace(outline.XMLURL); url != "" && !seen[url] {
+				seen[url] = true
+				urls = append(urls, url)
+			}
+			walk(outline.Outlines)
+		}
+	}
+	walk(doc.Outlines)
+
+	return urls, nil
+}
+
+func FetchOPML(r io.Reader, opts ...Option) ([]BlogPost, map[string]error) {
+	return NewFetcher(opts...).FetchOPML(context.Background(), r)
+}
+
+// FetchOPML fetches every feed in an OPML export, like FetchAllFeeds, and
+// reports each feed that failed under its URL. If the OPML itself can't be
+// read, that's the only error and it's under "".
+func (f *Fetcher) FetchOPML(ctx context.Context, r io.Reader) ([]BlogPost, map[string]error) {
+	urls, err := ParseOPML(r)
+	if err != nil {
+		return nil, map[string]error{"": err}
+	}
+
+	res, _ := f.fetchAll(ctx, f.requests(urls), false)
+	errs := make(map[string]error)
+	for i, err := range res.errs {
+		if err != nil {
+			errs[urls[i]] = err
+		}
+	}
+
+	capSources(res.groups, f.opts)
+	return mergePosts(flatten(res.groups), f.opts), errs
+}
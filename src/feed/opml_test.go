@@ -0,0 +1,67 @@
+package feed
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+func TestParseOPML(t *testing.T) {
+	f, err := os.Open("testdata/subscriptions.opml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	urls, err := ParseOPML(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://go.dev/blog/feed.atom", "https://alice.example.com/feed.xml", "https://bob.example.com/rss"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("got %q, want %q", urls, want)
+	}
+
+	if _, err := ParseOPML(strings.NewReader("")); err == nil {
+		t.Error("no error for an empty OPML")
+	}
+}
+
+func TestFetchOPML(t *testing.T) {
+	alice := feedtest.NewServer([]byte(postsFeed("alice", 2)))
+	defer alice.Close()
+	bob := feedtest.NewServer([]byte(postsFeed("bob", 3)))
+	defer bob.Close()
+	gone := feedtest.NewServer(nil, feedtest.WithStatus(410))
+	defer gone.Close()
+
+	opml := fmt.Sprintf(`<opml version="2.0"><body>
+<outline text="Alice" xmlUrl="%s"/>
+<outline text="Folder"><outline text="Bob" xmlUrl="%s"/><outline text="Dead" xmlUrl="%s"/></outline>
+</body></opml>`, alice.URL, bob.URL, gone.URL)
+
+	posts, errs := FetchOPML(strings.NewReader(opml), WithMaxPerSource(2))
+	// WithMaxPerSource counts too, bob's third post is cut
+	if len(posts) != 4 {
+		t.Errorf("got %d posts, want 4", len(posts))
+	}
+	for i := 1; i < len(posts); i++ {
+		if posts[i].PublishedAt.After(posts[i-1].PublishedAt) {
+			t.Errorf("post %d is out of order", i)
+		}
+	}
+
+	if len(errs) != 1 || !errors.Is(errs[gone.URL], ErrFeedGone) {
+		t.Errorf("got errors %v, want just ErrFeedGone for %s", errs, gone.URL)
+	}
+
+	_, errs = FetchOPML(strings.NewReader("<opml><body>"))
+	if errs[""] == nil {
+		t.Errorf("a broken OPML should be the only error, got %v", errs)
+	}
+}
@@ -0,0 +1,639 @@
+package feed
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Option tweaks how feeds are fetched and parsed.
+type Option func(*options)
+
+type options struct {
+	now func() time.Time
+
+	client       *http.Client
+	proxy        *url.URL
+	transport    *TransportConfig
+	cache        bool
+	store        Store
+	parsedCache  bool
+	headProbe    bool
+	forceRefresh bool
+	maxBodyBytes int64
+	headers      http.Header
+	timeout      time.Duration
+	retries      int
+	retryBackoff time.Duration
+
+	datePriority      []DateField
+	latestDate        bool
+	relativeDates     bool
+	timezone          *time.Location
+	faviconLookup     bool
+	fallbackSummary   string
+	metadataOnly      bool
+	contentImages     bool
+	contentCanonical  bool
+	keepRawXML        bool
+	namespaceReport   bool
+	slugs             bool
+	enclosureLinks    bool
+	fingerprint       bool
+	previews          bool
+	collapseBumps     bool
+	normalizeTitles   bool
+	asciiPunctuation  bool
+	paragraphs        bool
+	markdownSummaries bool
+	allowedTags       map[string]bool
+	sourceTitles      bool
+	boilerplate       []*regexp.Regexp
+	repeatedUnescape  bool
+	lenient           bool
+	generatorQuirks   bool
+	// where the body being parsed was fetched from, see fetchedFrom
+	feedURL string
+
+	summaryLength    int
+	minSummaryLength int
+	excerptLength    int
+	titleLength      int
+	ellipsis         string
+	strictTruncation bool
+
+	futurePolicy   FuturePolicy
+	untitledPolicy UntitledPolicy
+
+	authorAllow map[string]bool
+	authorDeny  map[string]bool
+
+	languageAllow   map[string]bool
+	languageMissing bool
+	languageDetect  bool
+
+	sortOrder     SortOrder
+	preserveOrder bool
+	mergeStrategy MergeStrategy
+	maxTotalPosts int
+	maxPerSource  int
+	maxConcurrent int
+	looseDedup    bool
+	budget        TotalBudget
+	onFeedDone    func(url string, postCount int, err error)
+	onResponse    func(url string, resp *http.Response)
+	onTiming      func(url string, t Timings)
+	seenSet       SeenSet
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		now:             time.Now,
+		datePriority:    defaultDatePriority,
+		fallbackSummary: "Visit post for details.",
+		summaryLength:   200,
+		excerptLength:   80,
+		ellipsis:        "...",
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithClock swaps out time.Now for anything that needs the current time,
+// like undated posts & relative dates. Mostly handy for freezing time.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// SortOrder picks which timestamp FetchAllFeeds sorts on.
+type SortOrder int
+
+const (
+	// SortByPublished sorts newest-published first (the default)
+	SortByPublished SortOrder = iota
+	// SortByUpdated sorts most-recently-updated first
+	SortByUpdated
+	// SortByActivity sorts on whichever of published & updated is later, so
+	// freshly edited old posts come back up. PublishedAt (and Date) still
+	// say when the post first went up.
+	SortByActivity
+)
+
+func WithSortOrder(order SortOrder) Option {
+	return func(o *options) {
+		o.sortOrder = order
+	}
+}
+
+// WithPreserveOrder skips date sorting for feeds that are in a deliberate
+// order, like a curated "top stories" list. FetchAllFeedsGrouped keeps each
+// feed in document order, and FetchAllFeeds & friends put the feeds one
+// after another in the order given (MergeInterleave is ignored). FetchFeed
+// already never sorts.
+func WithPreserveOrder() Option {
+	return func(o *options) {
+		o.preserveOrder = true
+	}
+}
+
+// DateField names one of the item elements a post date can come from.
+type DateField int
+
+const (
+	DatePubDate   DateField = iota // RSS <pubDate>
+	DateDate                       // <dc:date> and friends
+	DatePublished                  // atom <published>
+	DateUpdated                    // atom <updated>
+)
+
+func (field DateField) value(item Item) string {
+	switch field {
+	case DatePubDate:
+		return item.PubDate
+	case DateDate:
+		return item.Date
+	case DatePublished:
+		return item.Published
+	case DateUpdated:
+		return item.Updated
+	}
+	return ""
+}
+
+// pubDate, then date, then published, then updated
+var defaultDatePriority = []DateField{DatePubDate, DateDate, DatePublished, DateUpdated}
+
+// WithDatePriority changes the order date fields are tried in. The first one
+// that parses wins; fields left out are never looked at.
+func WithDatePriority(fields ...DateField) Option {
+	return func(o *options) {
+		o.datePriority = fields
+	}
+}
+
+// WithLatestDate uses the most recent of all the date fields that parse,
+// instead of the first one in priority order.
+func WithLatestDate() Option {
+	return func(o *options) {
+		o.latestDate = true
+	}
+}
+
+// FuturePolicy decides what happens to posts dated after "now", which are
+// usually scheduled posts leaking early or a server with a bad clock.
+type FuturePolicy int
+
+const (
+	// KeepFuture leaves future dates alone (the default)
+	KeepFuture FuturePolicy = iota
+	// ClampFuture pulls future dates back to now
+	ClampFuture
+	// DropFuture throws future-dated posts away, published or updated
+	DropFuture
+)
+
+func WithFuturePolicy(policy FuturePolicy) Option {
+	return func(o *options) {
+		o.futurePolicy = policy
+	}
+}
+
+// UntitledPolicy decides what happens to items with neither a title nor a
+// link, like status updates, which otherwise turn into posts that look
+// broken in most UIs.
+type UntitledPolicy int
+
+const (
+	// KeepUntitled leaves them as they are (the default)
+	KeepUntitled UntitledPolicy = iota
+	// DropUntitled throws them away
+	DropUntitled
+	// TitleFromSummary makes up a title from the first words of the
+	// description. items with no description either are left alone.
+	TitleFromSummary
+)
+
+// how long a made-up title gets, unless WithMaxTitleLength says shorter
+const untitledTitleLength = 60
+
+// WithUntitledPolicy picks what happens to items with neither a title nor a
+// link. KeepUntitled if never set.
+func WithUntitledPolicy(policy UntitledPolicy) Option {
+	return func(o *options) {
+		o.untitledPolicy = policy
+	}
+}
+
+func untitled(post BlogPost) bool {
+	return strings.TrimSpace(post.Title) == "" && post.Link == ""
+}
+
+// titleFromSummary is the TitleFromSummary title, "" without a description
+func titleFromSummary(item Item, o *options) string {
+	// the summary may well be the fallback placeholder, so start over from
+	// the description itself
+	po := *o
+	po.paragraphs = false
+	description, ok := getDescription(item, &po)
+	if !ok {
+		return ""
+	}
+
+	limit := untitledTitleLength
+	if o.titleLength > 0 && o.titleLength < limit {
+		limit = o.titleLength
+	}
+	return truncate(description, limit, o)
+}
+
+// applyFuturePolicy reports whether the post should be kept at all
+func (o *options) applyFuturePolicy(post *BlogPost) bool {
+	if o.futurePolicy == KeepFuture {
+		return true
+	}
+
+	now := o.now()
+	if !post.PublishedAt.After(now) && !post.UpdatedAt.After(now) {
+		return true
+	}
+	// a future update time throws activity sorting off just as badly
+	if o.futurePolicy == DropFuture {
+		return false
+	}
+
+	if post.PublishedAt.After(now) {
+		post.PublishedAt = now
+		post.Date = now
+	}
+	if post.UpdatedAt.After(now) {
+		post.UpdatedAt = now
+	}
+	return true
+}
+
+// WithRelativeDates lets date fields like "3 hours ago" or "yesterday" parse,
+// measured back from the time of parsing. Off by default since it's a guess.
+func WithRelativeDates() Option {
+	return func(o *options) {
+		o.relativeDates = true
+	}
+}
+
+// WithDefaultTimezone reads dates that don't say what zone they're in
+// ("2006-01-02 15:04:05", a bare "2006-01-02") as local time in loc instead
+// of UTC, for sites that write their own wall-clock time. A date with an
+// explicit offset or zone always keeps it.
+func WithDefaultTimezone(loc *time.Location) Option {
+	return func(o *options) {
+		o.timezone = loc
+	}
+}
+
+// WithMaxTotalPosts keeps only the newest n posts across all feeds, counted
+// after sorting and dedup. Zero (the default) means no limit.
+func WithMaxTotalPosts(n int) Option {
+	return func(o *options) {
+		o.maxTotalPosts = n
+	}
+}
+
+// WithMaxPerSource keeps only each feed's newest n posts, for "top 3 from
+// every site" layouts. Applies to FetchAllFeedsGrouped as well as the merged
+// results. Zero (the default) means no limit.
+func WithMaxPerSource(n int) Option {
+	return func(o *options) {
+		o.maxPerSource = n
+	}
+}
+
+// WithMaxConcurrentFetches caps how many feeds a Fetcher downloads at once,
+// across every call on it: a server running FetchAllFeeds for lots of users
+// at the same time shares n slots between all of them. The rest wait their
+// turn (or give up when their context does). 0 means no cap.
+func WithMaxConcurrentFetches(n int) Option {
+	return func(o *options) {
+		o.maxConcurrent = n
+	}
+}
+
+// WithLooseLinkDedup makes dedup treat links differing only in http vs
+// https or a "www." as the same post (see LooseLinkKey). Each post keeps
+// its Link as the feed gave it.
+func WithLooseLinkDedup() Option {
+	return func(o *options) {
+		o.looseDedup = true
+	}
+}
+
+// WithFallbackSummary replaces the "Visit post for details." placeholder used
+// when an item has no description. An empty string leaves Summary empty.
+func WithFallbackSummary(summary string) Option {
+	return func(o *options) {
+		o.fallbackSummary = summary
+	}
+}
+
+// WithOnFeedDone calls fn as each feed in FetchAllFeeds finishes, successful
+// or not. Calls never overlap, so fn doesn't need its own locking.
+func WithOnFeedDone(fn func(url string, postCount int, err error)) Option {
+	return func(o *options) {
+		o.onFeedDone = fn
+	}
+}
+
+// WithOnResponse calls fn with every feed response before its body is read,
+// for logging status, caching & rate-limit headers, or spotting redirects
+// via resp.Request.URL. fn gets a copy with an empty Body, so it can't eat
+// the feed. Calls can come from several goroutines at once.
+func WithOnResponse(fn func(url string, resp *http.Response)) Option {
+	return func(o *options) {
+		o.onResponse = fn
+	}
+}
+
+// WithAuthorAllowlist keeps only posts whose resolved author is one of names.
+// Matching ignores case.
+func WithAuthorAllowlist(names ...string) Option {
+	return func(o *options) {
+		o.authorAllow = nameSet(names)
+	}
+}
+
+// WithAuthorDenylist drops posts whose resolved author is one of names.
+// Matching ignores case.
+func WithAuthorDenylist(names ...string) Option {
+	return func(o *options) {
+		o.authorDeny = nameSet(names)
+	}
+}
+
+func nameSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	return set
+}
+
+func (o *options) authorAllowed(author string) bool {
+	author = strings.ToLower(strings.TrimSpace(author))
+	if len(o.authorAllow) > 0 && !o.authorAllow[author] {
+		return false
+	}
+	return !o.authorDeny[author]
+}
+
+// WithLanguageAllowlist drops every post from feeds whose declared language
+// isn't one of langs. "en" covers "en-US" & friends, "en-GB" only itself.
+// includeMissing decides what happens to feeds that don't declare one.
+func WithLanguageAllowlist(includeMissing bool, langs ...string) Option {
+	return func(o *options) {
+		o.languageAllow = nameSet(langs)
+		o.languageMissing = includeMissing
+	}
+}
+
+// WithLanguageDetection guesses each post's Language from its title &
+// description when the feed doesn't declare one, and WithLanguageAllowlist
+// then judges those posts one by one. The guesser only knows a handful of
+// languages (en, de, fr, es, it, pt, nl, plus a few non-Latin scripts) and
+// needs a sentence or two to go on; anything it isn't sure of is left "".
+func WithLanguageDetection() Option {
+	return func(o *options) {
+		o.languageDetect = true
+	}
+}
+
+func (o *options) languageAllowed(lang string) bool {
+	if len(o.languageAllow) == 0 {
+		return true
+	}
+
+	// en_US shows up too, even though it isn't a language tag
+	lang = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(lang)), "_", "-")
+	if lang == "" {
+		return o.languageMissing
+	}
+
+	for {
+		if o.languageAllow[lang] {
+			return true
+		}
+		i := strings.LastIndexByte(lang, '-')
+		if i < 0 {
+			return false
+		}
+		lang = lang[:i]
+	}
+}
+
+// WithBoilerplateTrimming strips trailing "Continue reading →" style junk from
+// summaries. Each pattern should be anchored to the end with $; with no
+// patterns a small set covering the common WordPress endings is used.
+func WithBoilerplateTrimming(patterns ...*regexp.Regexp) Option {
+	return func(o *options) {
+		if len(patterns) == 0 {
+			patterns = defaultBoilerplate
+		}
+		o.boilerplate = patterns
+	}
+}
+
+// WithMetadataOnly skips descriptions altogether, leaving Summary & Excerpt
+// empty. Titles, links and dates are all a table of contents needs, and
+// cleaning up content is most of the work of parsing a big feed.
+func WithMetadataOnly() Option {
+	return func(o *options) {
+		o.metadataOnly = true
+	}
+}
+
+// WithContentImages falls back to the first image in a post's content when
+// the feed has no media:thumbnail for it. Off by default since it means
+// scanning the content HTML.
+func WithContentImages() Option {
+	return func(o *options) {
+		o.contentImages = true
+	}
+}
+
+// WithContentCanonical looks for a <link rel="canonical"> in each item's
+// content and, when there is one, dedups on it instead of the post's link,
+// so an article republished across sites only shows up once.
+func WithContentCanonical() Option {
+	return func(o *options) {
+		o.contentCanonical = true
+	}
+}
+
+// WithSummaryLength sets how many characters Summary is cut to (default 200).
+// 0 or less leaves it uncut.
+func WithSummaryLength(n int) Option {
+	return func(o *options) {
+		o.summaryLength = n
+	}
+}
+
+// WithMinSummaryLength skips descriptions that come out shorter than n
+// characters once cleaned, moving on to the item's other text fields and
+// then the fallback summary.
+func WithMinSummaryLength(n int) Option {
+	return func(o *options) {
+		o.minSummaryLength = n
+	}
+}
+
+// WithMaxTitleLength cuts titles longer than n characters the same way
+// summaries are cut: on a word boundary, with the ellipsis. 0 (the default)
+// leaves titles alone.
+func WithMaxTitleLength(n int) Option {
+	return func(o *options) {
+		o.titleLength = n
+	}
+}
+
+// WithExcerptLength sets how many characters Excerpt is cut to (default 80).
+// 0 or less leaves it uncut.
+func WithExcerptLength(n int) Option {
+	return func(o *options) {
+		o.excerptLength = n
+	}
+}
+
+// WithEllipsis changes the "..." tacked onto truncated text, e.g. to "…".
+func WithEllipsis(marker string) Option {
+	return func(o *options) {
+		o.ellipsis = marker
+	}
+}
+
+// WithStrictTruncation counts the ellipsis against the length limit, so
+// truncated text never ends up longer than the limit.
+func WithStrictTruncation() Option {
+	return func(o *options) {
+		o.strictTruncation = true
+	}
+}
+
+// WithLenient tries to rescue badly served feeds instead of failing them:
+// XML wrapped in a JSONP callback, or escaped inside an HTML <pre> block,
+// gets unwrapped before parsing.
+func WithLenient() Option {
+	return func(o *options) {
+		o.lenient = true
+	}
+}
+
+// WithRepeatedUnescape decodes entities more than once, for feeds that
+// double-encode (so "&amp;amp;" shows up as "&amp;"). Off by default, because
+// an article that legitimately talks about "&amp;lt;" would lose it.
+func WithRepeatedUnescape() Option {
+	return func(o *options) {
+		o.repeatedUnescape = true
+	}
+}
+
+// WithGeneratorQuirks looks at the feed's <generator> and switches on the
+// workarounds that software is known to need. Right now that's boilerplate
+// trimming for WordPress.
+func WithGeneratorQuirks() Option {
+	return func(o *options) {
+		o.generatorQuirks = true
+	}
+}
+
+// WithParagraphs keeps paragraph & line breaks in Summary and Excerpt
+// instead of squashing everything onto one line.
+func WithParagraphs() Option {
+	return func(o *options) {
+		o.paragraphs = true
+	}
+}
+
+// WithTitleNormalization collapses newlines, tabs & runs of spaces in titles
+// into single spaces, the same way summaries already get cleaned.
+func WithTitleNormalization() Option {
+	return func(o *options) {
+		o.normalizeTitles = true
+	}
+}
+
+// WithMarkdownSummaries turns the description's links, bold, italics, code &
+// list items into markdown for Summary and Excerpt, instead of stripping
+// them to plain text. The length limits count visible text only. Boilerplate
+// trimming doesn't apply, and HTML too broken to read falls back to plain text.
+func WithMarkdownSummaries() Option {
+	return func(o *options) {
+		o.markdownSummaries = true
+	}
+}
+
+// WithAllowedTags fills BlogPost.SummaryHTML with the description cut to
+// the summary length, keeping only tags (e.g. "a", "em", "p", "ul", "li")
+// and dropping the rest. Links & images keep only safe attributes and
+// http(s) URLs, and script, style & iframe go away with their contents
+// even if listed.
+func WithAllowedTags(tags ...string) Option {
+	return func(o *options) {
+		o.allowedTags = nameSet(tags)
+	}
+}
+
+// WithSlugs fills in BlogPost.Slug. Two posts can end up with the same slug,
+// sorting that out is up to you.
+func WithSlugs() Option {
+	return func(o *options) {
+		o.slugs = true
+	}
+}
+
+// WithEnclosureLinks uses an item's first enclosure as its Link when it has
+// no link of its own, as in image & video feeds. Off by default, since on a
+// blog that would turn a post into a link to its attachment.
+func WithEnclosureLinks() Option {
+	return func(o *options) {
+		o.enclosureLinks = true
+	}
+}
+
+// WithASCIIPunctuation turns curly quotes, dashes, ellipses & non-breaking
+// spaces in titles & summaries into their plain ASCII versions, for sinks
+// that only cope with ASCII. Off by default, typography is nice to keep.
+func WithASCIIPunctuation() Option {
+	return func(o *options) {
+		o.asciiPunctuation = true
+	}
+}
+
+// WithPreviews fills in BlogPost.Preview, which means another pass over
+// every post's content.
+func WithPreviews() Option {
+	return func(o *options) {
+		o.previews = true
+	}
+}
+
+// WithNamespaceReport fills in FeedInfo.UnhandledNamespaces, for finding out
+// what extensions a feed uses that harvest ignores. Costs a second pass over
+// every document.
+func WithNamespaceReport() Option {
+	return func(o *options) {
+		o.namespaceReport = true
+	}
+}
+
+// WithRawItemXML copies each item's raw inner XML into BlogPost.RawXML, for
+// figuring out why something mapped weirdly. Off by default since it roughly
+// doubles what every post holds onto.
+func WithRawItemXML() Option {
+	return func(o *options) {
+		o.keepRawXML = true
+	}
+}
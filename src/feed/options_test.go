@@ -0,0 +1,182 @@
+package feed
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+func TestClock(t *testing.T) {
+	frozen := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := WithClock(func() time.Time { return frozen })
+
+	body := `<rss version="2.0"><channel><title>Clocked</title>
+<item><title>Undated</title><link>https://example.com/1</link></item>
+<item><title>Past</title><link>https://example.com/2</link><pubDate>Thu, 29 Feb 2024 12:00:00 GMT</pubDate></item>
+<item><title>Scheduled</title><link>https://example.com/3</link><pubDate>Sat, 02 Mar 2024 12:00:00 GMT</pubDate></item>
+</channel></rss>`
+	srv := feedtest.NewServer([]byte(body))
+	defer srv.Close()
+
+	past := time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)
+	scheduled := time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		opts   []Option
+		titles []string
+		dates  []time.Time
+	}{
+		{"undated gets the clock", nil, []string{"Undated", "Past", "Scheduled"}, []time.Time{frozen, past, scheduled}},
+		{"clamp to the clock", []Option{WithFuturePolicy(ClampFuture)}, []string{"Undated", "Past", "Scheduled"}, []time.Time{frozen, past, frozen}},
+		{"drop after the clock", []Option{WithFuturePolicy(DropFuture)}, []string{"Undated", "Past"}, []time.Time{frozen, past}},
+	}
+	for _, tt := range tests {
+		f := NewFetcher(append([]Option{clock}, tt.opts...)...)
+		posts, err := f.FetchFeed(srv.URL)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(posts) != len(tt.titles) {
+			t.Fatalf("%s: got %d posts, want %d", tt.name, len(posts), len(tt.titles))
+		}
+		for i, post := range posts {
+			if post.Title != tt.titles[i] || !post.PublishedAt.Equal(tt.dates[i]) {
+				t.Errorf("%s: post %d is %q at %v, want %q at %v", tt.name, i, post.Title, post.PublishedAt, tt.titles[i], tt.dates[i])
+			}
+		}
+	}
+
+	// & the default really is the wall clock
+	before := time.Now()
+	posts, err := FetchFeed(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := posts[0].PublishedAt; got.Before(before) || got.After(time.Now()) {
+		t.Errorf("undated post at %v, want about now", got)
+	}
+}
+
+func TestFuturePolicy(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	nextYear := now.AddDate(1, 0, 0)
+	body := `<rss version="2.0"><channel><title>Leaky</title>
+<item><title>Yesterday's post</title><link>https://example.com/1</link><pubDate>Thu, 29 Feb 2024 12:00:00 GMT</pubDate></item>
+<item><title>Scheduled for next year</title><link>https://example.com/2</link><pubDate>Sat, 01 Mar 2025 12:00:00 GMT</pubDate><updated>2025-03-01T12:00:00Z</updated></item>
+<item><title>Edited next year</title><link>https://example.com/3</link><pubDate>Wed, 28 Feb 2024 12:00:00 GMT</pubDate><updated>2025-03-01T12:00:00Z</updated></item>
+</channel></rss>`
+	srv := feedtest.NewServer([]byte(body))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		policy FuturePolicy
+		// newest first, so a future post is on top unless it's dealt with
+		first   string
+		count   int
+		topDate time.Time
+	}{
+		{"keep", KeepFuture, "Scheduled for next year", 3, nextYear},
+		{"clamp", ClampFuture, "Scheduled for next year", 3, now},
+		{"drop", DropFuture, "Yesterday's post", 1, time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		posts := FetchAllFeeds([]string{srv.URL}, WithFuturePolicy(tt.policy), WithClock(func() time.Time { return now }))
+		if len(posts) != tt.count {
+			t.Fatalf("%s: got %d posts, want %d", tt.name, len(posts), tt.count)
+		}
+		top := posts[0]
+		if top.Title != tt.first || !top.PublishedAt.Equal(tt.topDate) {
+			t.Errorf("%s: top post %q at %v, want %q at %v", tt.name, top.Title, top.PublishedAt, tt.first, tt.topDate)
+		}
+		for _, post := range posts {
+			if post.UpdatedAt.After(now) && tt.policy != KeepFuture {
+				t.Errorf("%s: %q UpdatedAt %v is still in the future", tt.name, post.Title, post.UpdatedAt)
+			}
+		}
+		// & sorting by activity doesn't get thrown either
+		sortPosts(posts, SortByActivity)
+		if tt.policy != KeepFuture && posts[0].Title != tt.first {
+			t.Errorf("%s: most active post is %q, want %q", tt.name, posts[0].Title, tt.first)
+		}
+	}
+}
+
+func TestLanguageAllowlist(t *testing.T) {
+	feedIn := func(name, lang string) *feedtest.Server {
+		language := ""
+		if lang != "" {
+			language = "<language>" + lang + "</language>"
+		}
+		return feedtest.NewServer([]byte(`<rss version="2.0"><channel><title>` + name + `</title>` + language + `
+<item><title>` + name + ` post</title><link>https://` + name + `.example.com/1</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate></item>
+</channel></rss>`))
+	}
+	servers := []*feedtest.Server{feedIn("american", "en-US"), feedIn("british", "en_GB"), feedIn("german", "de"), feedIn("unknown", "")}
+	var urls []string
+	for _, srv := range servers {
+		defer srv.Close()
+		urls = append(urls, srv.URL)
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want map[string]bool
+	}{
+		{"no allowlist", nil, map[string]bool{"american": true, "british": true, "german": true, "unknown": true}},
+		{"english, missing included", []Option{WithLanguageAllowlist(true, "en")}, map[string]bool{"american": true, "british": true, "unknown": true}},
+		{"english, missing excluded", []Option{WithLanguageAllowlist(false, "EN")}, map[string]bool{"american": true, "british": true}},
+		{"british only", []Option{WithLanguageAllowlist(false, "en-GB")}, map[string]bool{"british": true}},
+		{"german or british", []Option{WithLanguageAllowlist(false, "de", "en-gb")}, map[string]bool{"british": true, "german": true}},
+	}
+	for _, tt := range tests {
+		got := make(map[string]bool)
+		for _, post := range FetchAllFeeds(urls, tt.opts...) {
+			got[post.SourceTitle] = true
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got posts from %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUntitledPolicy(t *testing.T) {
+	const body = `<rss version="2.0"><channel><title>Status</title><link>https://status.example.com/</link>
+<item><title>A real post</title><link>https://status.example.com/1</link></item>
+<item><description>Just shipped the new release &amp; it went better than expected, thanks everyone who helped test it</description></item>
+<item><guid isPermaLink="false">status-3</guid></item>
+<item><link>https://status.example.com/4</link></item>
+</channel></rss>`
+
+	tests := []struct {
+		name   string
+		opts   []Option
+		titles []string
+	}{
+		{"keep by default", nil, []string{"A real post", "", "", ""}},
+		{"keep", []Option{WithUntitledPolicy(KeepUntitled)}, []string{"A real post", "", "", ""}},
+		// a link alone is enough to not count as untitled
+		{"drop", []Option{WithUntitledPolicy(DropUntitled)}, []string{"A real post", ""}},
+		{"title from summary", []Option{WithUntitledPolicy(TitleFromSummary)},
+			[]string{"A real post", "Just shipped the new release & it went better than expected...", "", ""}},
+		{"title from summary, shorter max", []Option{WithUntitledPolicy(TitleFromSummary), WithMaxTitleLength(20)},
+			[]string{"A real post", "Just shipped the new...", "", ""}},
+	}
+	for _, tt := range tests {
+		posts, err := ParseFeed(strings.NewReader(body), tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var titles []string
+		for _, post := range posts {
+			titles = append(titles, post.Title)
+		}
+		if !reflect.DeepEqual(titles, tt.titles) {
+			t.Errorf("%s: got %q, want %q", tt.name, titles, tt.titles)
+		}
+	}
+}
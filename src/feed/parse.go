@@ -0,0 +1,808 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var dateFormats = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z",
+	"2006-01-02 15:04:05 -0700",
+	"02 Jan 2006 15:04 -0700",
+	"Mon, 02 Jan 2006 15:04:05 GMT",
+	"02 Jan 2006 15:04 +0000",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"January 2, 2006",
+}
+
+// the dateFormats with no offset in them, which WithDefaultTimezone applies to
+var zonelessFormats = map[string]bool{
+	"2006-01-02 15:04:05": true,
+	"2006-01-02T15:04:05": true,
+	"2006-01-02":          true,
+	"January 2, 2006":     true,
+}
+
+// nothing in dateFormats comes anywhere near this long, so anything bigger
+// is junk and not worth running through every layout
+const maxDateLength = 128
+
+func parseDateString(dateStr string, o *options) (time.Time, bool) {
+	// feeds love wrapping dates in newlines & indentation
+	dateStr = strings.TrimSpace(dateStr)
+	if dateStr == "" || len(dateStr) > maxDateLength {
+		return time.Time{}, false
+	}
+
+	for _, format := range dateFormats {
+		if zonelessFormats[format] && o.timezone != nil {
+			if t, err := time.ParseInLocation(format, dateStr, o.timezone); err == nil {
+				return t, true
+			}
+			continue
+		}
+		if t, err := time.Parse(format, dateStr); err == nil {
+			return t, true
+		}
+	}
+
+	if o.relativeDates {
+		return parseRelativeDate(dateStr, o.now())
+	}
+
+	return time.Time{}, false
+}
+
+// the bool is false when nothing parsed and we fell back to now
+func parseDate(item Item, o *options) (time.Time, bool) {
+	var (
+		latest time.Time
+		found  bool
+	)
+
+	for _, field := range o.datePriority {
+		dateStr := field.value(item)
+		if dateStr == "" {
+			continue
+		}
+
+		t, ok := parseDateString(dateStr, o)
+		if !ok {
+			continue
+		}
+		if !o.latestDate {
+			return t, true
+		}
+		if !found || t.After(latest) {
+			latest, found = t, true
+		}
+	}
+
+	if found {
+		return latest, true
+	}
+
+	log.Printf("warn: Could not parse any date from item %s", item.Title)
+	return o.now(), false
+}
+
+// only Updated counts here, anything else is a publish date
+func parseUpdated(item Item, published time.Time, o *options) time.Time {
+	if item.Updated != "" {
+		if t, ok := parseDateString(item.Updated, o); ok {
+			return t
+		}
+	}
+
+	return published
+}
+
+func getTitle(item Item, o *options) string {
+	title := o.typography(item.Title)
+	if o.normalizeTitles {
+		// multi-line CDATA titles, tabs, runs of spaces... all one line now
+		title = strings.TrimSpace(wsRegex.ReplaceAllString(title, " "))
+	}
+	if o.titleLength > 0 {
+		title = truncate(title, o.titleLength, o)
+	}
+	return title
+}
+
+// getDescription returns the first usable description, cleaned up but not
+// yet truncated
+func getDescription(item Item, o *options) (string, bool) {
+	_, cleaned, ok := pickDescription(item, o)
+	return cleaned, ok
+}
+
+// pickDescription is getDescription that also hands back the raw HTML the
+// description came from
+func pickDescription(item Item, o *options) (raw, cleaned string, ok bool) {
+	// podcast feeds often only fill in the itunes fields, so they go last
+	candidates := []string{
+		item.Description,
+		textIn(item.Summaries, ""),
+		item.Content.html(),
+		item.Encoded,
+		textIn(item.Summaries, itunesNS),
+		textIn(item.Subtitles, itunesNS),
+	}
+
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+
+		// a lone "." or an image-only description isn't a summary, try the next one
+		cleaned := stripMarkdown(cleanHTML(candidate, o))
+		if cleaned == "" || utf8.RuneCountInString(cleaned) < o.minSummaryLength {
+			continue
+		}
+		return candidate, cleaned, true
+	}
+
+	return "", "", false
+}
+
+// getSummaryHTML is the description cut to summaryLength with only the
+// WithAllowedTags tags left in it
+func getSummaryHTML(item Item, link string, o *options) string {
+	if o.metadataOnly || o.allowedTags == nil {
+		return ""
+	}
+
+	raw, description, ok := pickDescription(item, o)
+	if !ok {
+		return html.EscapeString(o.fallbackSummary)
+	}
+	if sanitized, ok := sanitizeHTML(raw, link, o.allowedTags, o.summaryLength, o); ok {
+		return sanitized
+	}
+	return html.EscapeString(truncate(description, o.summaryLength, o))
+}
+
+func getSummary(item Item, link string, o *options) (summary, excerpt string) {
+	// cleaning is the expensive bit, skip it entirely when nobody wants it
+	if o.metadataOnly {
+		return "", ""
+	}
+
+	raw, description, ok := pickDescription(item, o)
+	if !ok {
+		return o.fallbackSummary, o.fallbackSummary
+	}
+
+	if o.markdownSummaries {
+		summary, ok1 := htmlToMarkdown(raw, link, o.summaryLength, o)
+		excerpt, ok2 := htmlToMarkdown(raw, link, o.excerptLength, o)
+		if ok1 && ok2 {
+			return summary, excerpt
+		}
+	}
+
+	return truncate(description, o.summaryLength, o), truncate(description, o.excerptLength, o)
+}
+
+// getContentLength is how long the full article is as plain text, in runes.
+// the full content fields win over description since that's often a teaser.
+func getContentLength(item Item, o *options) int {
+	if o.metadataOnly {
+		return 0
+	}
+
+	for _, content := range []string{item.Encoded, item.Content.html()} {
+		if content != "" {
+			return utf8.RuneCountInString(stripMarkdown(cleanHTML(content, o)))
+		}
+	}
+
+	description, _ := getDescription(item, o)
+	return utf8.RuneCountInString(description)
+}
+
+func categoryTags(categories []Category) []Tag {
+	var tags []Tag
+	seen := make(map[Tag]bool)
+	for _, category := range categories {
+		domain := strings.TrimSpace(category.Domain)
+		if domain == "" {
+			domain = strings.TrimSpace(category.Scheme)
+		}
+
+		// some feeds cram "go, rust, linux" into a single element
+		for _, label := range strings.Split(category.Value(), ",") {
+			tag := Tag{Label: strings.TrimSpace(label), Domain: domain}
+			if tag.Label == "" || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+func getGUID(item Item, link string) string {
+	if guid := strings.TrimSpace(item.GUID.Text); guid != "" {
+		return guid
+	}
+	if item.ID != "" {
+		return strings.TrimSpace(item.ID)
+	}
+	return link
+}
+
+// getLink picks the article itself out of however many <link>s an item has:
+// an html rel="alternate" first, then a plain rel-less link (which is what RSS
+// gives us), then any other alternate. enclosure, self, related, replies etc.
+// point somewhere else and are never used. with no link at all, a permalink
+// guid is the article.
+func getLink(item Item) string {
+	var plain, alternate string
+	for _, link := range item.Links {
+		url := link.URL()
+		if url == "" {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(link.Rel)) {
+		case "alternate":
+			mediaType := strings.ToLower(link.Type)
+			if mediaType == "" || mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+				return url
+			}
+			if alternate == "" {
+				alternate = url
+			}
+		case "":
+			if plain == "" {
+				plain = url
+			}
+		}
+	}
+
+	if plain != "" {
+		return plain
+	}
+	if alternate != "" {
+		return alternate
+	}
+	return item.GUID.permalink()
+}
+
+func getCommentFeed(item Item, link, scheme string) string {
+	url := withScheme(item.CommentRss, scheme)
+	if url == "" {
+		return ""
+	}
+	return resolveURL(url, link)
+}
+
+// enclosureLink is the first enclosure's URL, for media feeds whose items
+// have nothing else to click on
+func enclosureLink(item Item, scheme string) string {
+	for _, enclosure := range item.Enclosures {
+		if url := withScheme(enclosure.URL, scheme); url != "" {
+			return url
+		}
+	}
+	return ""
+}
+
+func getEnclosures(item Item) []Enclosure {
+	var enclosures []Enclosure
+	for _, raw := range item.Enclosures {
+		if strings.TrimSpace(raw.URL) == "" {
+			continue
+		}
+
+		// "unknown", "", "-1"... all of these show up in the wild
+		length, err := strconv.ParseInt(strings.TrimSpace(raw.Length), 10, 64)
+		if err != nil || length < 0 {
+			length = 0
+		}
+
+		enclosures = append(enclosures, Enclosure{
+			URL:    strings.TrimSpace(raw.URL),
+			Type:   strings.TrimSpace(raw.Type),
+			Length: length,
+		})
+	}
+
+	return enclosures
+}
+
+func getCanonical(item Item, link string, o *options) string {
+	if !o.contentCanonical {
+		return ""
+	}
+
+	for _, content := range []string{item.Encoded, item.Content.html(), item.Description} {
+		if canonical := canonicalLink(content, link); canonical != "" {
+			return canonical
+		}
+	}
+	return ""
+}
+
+func getImage(item Item, link string, o *options) string {
+	for _, thumb := range item.Thumbnails {
+		if url := strings.TrimSpace(thumb.URL); url != "" {
+			return resolveURL(url, link)
+		}
+	}
+
+	if !o.contentImages {
+		return ""
+	}
+
+	for _, content := range []string{item.Encoded, item.Content.html(), item.Description} {
+		if img := firstImage(content, link); img != "" {
+			return img
+		}
+	}
+
+	return ""
+}
+
+// itemWarnings is everything about an item a feed owner would want to fix
+func itemWarnings(item Item, post BlogPost, dated bool) []string {
+	var warnings []string
+	if !dated {
+		warnings = append(warnings, "no parseable date, using fetch time")
+	}
+	if strings.TrimSpace(post.Title) == "" {
+		warnings = append(warnings, "missing title")
+	}
+	if post.Link == "" {
+		warnings = append(warnings, "missing link")
+	}
+	for _, raw := range item.Enclosures {
+		length := strings.TrimSpace(raw.Length)
+		if n, err := strconv.ParseInt(length, 10, 64); length != "" && (err != nil || n < 0) {
+			warnings = append(warnings, fmt.Sprintf("bad enclosure length %q", raw.Length))
+		}
+	}
+
+	return warnings
+}
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// XML 1.0 doesn't allow C0 control characters other than tab & newlines
+// anywhere, so one stray \x0b in a description would fail the whole feed.
+// they can't mean anything, so out they go.
+func dropIllegalControls(body []byte) []byte {
+	if bytes.IndexFunc(body, illegalControl) < 0 {
+		return body
+	}
+	return bytes.Map(func(r rune) rune {
+		if illegalControl(r) {
+			return -1
+		}
+		return r
+	}, body)
+}
+
+func illegalControl(r rune) bool {
+	return r < 0x20 && r != '\t' && r != '\n' && r != '\r'
+}
+
+// ParseFeedFunc decodes an RSS or Atom document and hands each post to fn
+// as soon as its item has been read, stopping at the first error fn
+// returns. Nothing holds on to the items, so a huge feed (or a slow
+// reader) costs one item's worth of memory at a time. If the document
+// turns out to be broken further down, fn has already seen the posts
+// before the break when the error comes back.
+func ParseFeedFunc(r io.Reader, fn func(BlogPost) error, opts ...Option) error {
+	_, err := parseFeed(r, newOptions(opts), fn)
+	return err
+}
+
+func parseFeed(r io.Reader, o *options, fn func(BlogPost) error) (FeedInfo, error) {
+	stream, body, err := feedReader(r, o)
+	if err != nil {
+		return FeedInfo{}, fmt.Errorf("reading feed: %w", err)
+	}
+
+	// fingerprinted as they go out, so only posts the caller actually gets count
+	var keys []string
+	if o.fingerprint {
+		next := fn
+		fn = func(post BlogPost) error {
+			keys = append(keys, fingerprintKey(post))
+			return next(post)
+		}
+	}
+
+	s := &feedStream{d: xml.NewDecoder(stream), o: o, emit: fn}
+	var bumps *bumpCollapser
+	if o.collapseBumps {
+		bumps = &bumpCollapser{fn: fn}
+		s.emit = bumps.add
+	}
+
+	err = s.run()
+	var readErr readFailure
+	switch {
+	case err == nil:
+	case s.emitErr != nil:
+		return s.finish(body), err
+	case errors.As(err, &readErr):
+		return FeedInfo{}, fmt.Errorf("reading feed: %w", readErr.err)
+	case errors.Is(err, ErrEmptyFeed):
+		return FeedInfo{}, parseError(err)
+	case isTruncated(err) && o.lenient:
+		log.Printf("warn: feed is cut off, keeping the items before the cut")
+	case isTruncated(err):
+		return FeedInfo{}, parseError(fmt.Errorf("parsing feed: %w: %w", ErrTruncatedFeed, err))
+	default:
+		return FeedInfo{}, parseError(fmt.Errorf("parsing feed: %w", err))
+	}
+
+	info := s.finish(body)
+	if s.skip {
+		return info, nil
+	}
+	if s.items == 0 {
+		return info, parseError(ErrNoItemsFound)
+	}
+	if bumps != nil {
+		if err := bumps.flush(); err != nil {
+			return info, err
+		}
+	}
+
+	if o.fingerprint {
+		info.Fingerprint = fingerprint(keys)
+	}
+	return info, nil
+}
+
+// finish is the FeedInfo for the whole header, including anything that
+// came after the items (RSS <image> likes to trail)
+func (s *feedStream) finish(body []byte) FeedInfo {
+	if !s.started {
+		s.begin()
+	}
+	if len(s.feed.Channels) > 0 {
+		s.feed.Channel = s.feed.Channels[0]
+	}
+
+	info := getFeedInfo(s.feed, s.o)
+	if s.o.namespaceReport {
+		info.UnhandledNamespaces = unhandledNamespaces(body)
+	}
+	info.EstimatedUpdateInterval = medianInterval(s.dates)
+	return info
+}
+
+// itemPost maps one item onto a BlogPost, before any of the filters get a
+// say. source is the title of the feed (or channel) it came from, language
+// the feed's declared one, scheme what protocol-relative URLs get.
+func itemPost(item Item, source, language, scheme string, o *options) (BlogPost, bool) {
+	link := withScheme(getLink(item), scheme)
+	if link == "" && o.enclosureLinks {
+		link = enclosureLink(item, scheme)
+	}
+	published, dated := parseDate(item, o)
+	summary, excerpt := getSummary(item, link, o)
+	post := BlogPost{
+		Title:          getTitle(item, o),
+		Link:           link,
+		CanonicalURL:   getCanonical(item, link, o),
+		GUID:           getGUID(item, link),
+		Date:           published,
+		PublishedAt:    published,
+		UpdatedAt:      parseUpdated(item, published, o),
+		Author:         getAuthor(item, source),
+		Authors:        getAuthors(item),
+		AuthorDetail:   firstAuthor(getAuthorDetails(item)),
+		Summary:        summary,
+		Excerpt:        excerpt,
+		SummaryHTML:    getSummaryHTML(item, link, o),
+		ContentLength:  getContentLength(item, o),
+		Language:       language,
+		Tags:           categoryTags(item.Categories),
+		SourceTitle:    source,
+		Origin:         getOrigin(item),
+		Location:       getLocation(item),
+		CommentFeedURL: getCommentFeed(item, link, scheme),
+		Podcast:        getPodcastEpisode(item, link, scheme),
+		Enclosures:     getEnclosures(item),
+		Image:          getImage(item, link, o),
+		dated:          dated,
+	}
+	post.Image = withScheme(post.Image, scheme)
+	for i := range post.Enclosures {
+		post.Enclosures[i].URL = withScheme(post.Enclosures[i].URL, scheme)
+	}
+	post.Warnings = itemWarnings(item, post, dated)
+	if o.untitledPolicy == TitleFromSummary && untitled(post) {
+		post.Title = titleFromSummary(item, o)
+	}
+	if o.slugs {
+		post.Slug = slugify(post.Title)
+	}
+	if o.previews {
+		post.Preview = getPreview(item, link, o)
+	}
+	if o.keepRawXML {
+		post.RawXML = item.Raw
+	}
+
+	return post, dated
+}
+
+const (
+	// fewer dated posts than this and any estimate is a guess
+	minIntervalSamples = 3
+	// only recent posting habits count
+	maxIntervalSamples = 20
+)
+
+// medianInterval is the typical gap between the newest posts, or 0 when
+// there aren't enough dated ones to say
+func medianInterval(dates []time.Time) time.Duration {
+	if len(dates) < minIntervalSamples {
+		return 0
+	}
+
+	sorted := append([]time.Time(nil), dates...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].After(sorted[j]) })
+	if len(sorted) > maxIntervalSamples {
+		sorted = sorted[:maxIntervalSamples]
+	}
+
+	gaps := make([]time.Duration, len(sorted)-1)
+	for i := range gaps {
+		gaps[i] = sorted[i].Sub(sorted[i+1])
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+
+	mid := len(gaps) / 2
+	if len(gaps)%2 == 0 {
+		return (gaps[mid-1] + gaps[mid]) / 2
+	}
+	return gaps[mid]
+}
+
+// atom 0.3 called them issued & modified, map them onto the 1.0 names so
+// date priority treats both versions the same
+func atom03Dates(item Item) Item {
+	if item.Published == "" {
+		item.Published = item.Issued
+	}
+	if item.Updated == "" {
+		item.Updated = item.Modified
+	}
+	return item
+}
+
+// feedFormat works out what kind of document this is from its root element
+func feedFormat(feed Feed) (format, version string) {
+	version = strings.TrimSpace(feed.Version)
+
+	switch strings.ToLower(feed.XMLName.Local) {
+	case "rss":
+		format = "rss"
+	case "feed":
+		format = "atom"
+		if version == "" && feed.XMLName.Space == atomNS {
+			version = "1.0"
+		}
+	case "rdf":
+		format = "rdf"
+		if version == "" && feed.XMLName.Space == rdfNS {
+			version = "1.0"
+		}
+	}
+
+	return format, version
+}
+
+// withQuirks turns on the workarounds a known generator needs, on a copy so
+// other feeds sharing the options aren't affected
+func withQuirks(o *options, generator string) *options {
+	quirked := *o
+	if strings.Contains(strings.ToLower(generator), "wordpress") && quirked.boilerplate == nil {
+		quirked.boilerplate = defaultBoilerplate
+	}
+
+	return &quirked
+}
+
+func getFeedInfo(feed Feed, o *options) FeedInfo {
+	format, version := feedFormat(feed)
+	info := FeedInfo{
+		Format:      format,
+		Version:     version,
+		Title:       feed.Channel.Title,
+		Description: feed.Channel.Description,
+	}
+
+	if info.Title == "" {
+		info.Title = feed.Title
+	}
+
+	links := feed.Channel.Links
+	if len(links) == 0 {
+		links = feed.Links
+	}
+	for _, link := range links {
+		url := link.URL()
+		if url == "" {
+			continue
+		}
+
+		switch strings.ToLower(link.Rel) {
+		case "", "alternate":
+			if info.Link == "" {
+				info.Link = url
+			}
+		case "self":
+			if info.SelfURL == "" {
+				info.SelfURL = url
+			}
+		case "hub":
+			if info.HubURL == "" {
+				info.HubURL = url
+			}
+		}
+	}
+	// relative ones get another go against the URL they were actually
+	// fetched from, see fetchFeed
+	info.Icon, info.Logo = getFeedImages(feed)
+	scheme := feedScheme(o.feedURL, info.SelfURL, feed.Base, info.Link)
+	info.Link = withScheme(info.Link, scheme)
+	info.SelfURL = withScheme(info.SelfURL, scheme)
+	info.HubURL = withScheme(info.HubURL, scheme)
+	info.Icon.URL = withScheme(info.Icon.URL, scheme)
+	info.Logo.URL = withScheme(info.Logo.URL, scheme)
+
+	base := withScheme(feed.Base, scheme)
+	if base == "" {
+		base = info.SelfURL
+	}
+	if base == "" {
+		base = info.Link
+	}
+	info.resolveImages(base)
+	info.FaviconURL = siteFavicon(info.Link)
+
+	info.Generator = feed.Channel.Generator.String()
+	if info.Generator == "" {
+		info.Generator = feed.Generator.String()
+	}
+
+	info.Language = strings.TrimSpace(feed.Channel.Language)
+	if info.Language == "" {
+		info.Language = strings.TrimSpace(feed.Lang)
+	}
+
+	info.ManagingEditor = feed.Channel.ManagingEditor.author()
+	info.WebMaster = feed.Channel.WebMaster.author()
+	info.Podcast = getPodcastInfo(feed.Channel)
+
+	info.PubDate, _ = parseDateString(feed.Channel.PubDate, o)
+	info.LastBuildDate, _ = parseDateString(feed.Channel.LastBuildDate, o)
+	for _, updated := range []string{feed.Updated, feed.Modified, feed.Channel.Updated} {
+		if t, ok := parseDateString(updated, o); ok {
+			info.FeedUpdated = t
+			break
+		}
+	}
+
+	info.Categories = categoryTags(feed.Channel.Categories)
+	if len(info.Categories) == 0 {
+		info.Categories = categoryTags(feed.Categories)
+	}
+
+	return info
+}
+
+// ParseItem parses a lone <item> or <entry>, the kind a WebSub push
+// delivers, into a BlogPost the same way ParseFeed would. Without a feed
+// around it there's no feed title for SourceTitle or the author fallback,
+// and the filters (WithAuthorAllowlist, WithFuturePolicy, ...) don't apply.
+func ParseItem(r io.Reader, opts ...Option) (BlogPost, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return BlogPost{}, fmt.Errorf("reading item: %w", err)
+	}
+	body = dropIllegalControls(bytes.TrimPrefix(body, utf8BOM))
+	if len(bytes.TrimSpace(body)) == 0 {
+		return BlogPost{}, parseError(ErrEmptyFeed)
+	}
+
+	o := newOptions(opts)
+	s := &feedStream{d: xml.NewDecoder(bytes.NewReader(body))}
+	start, err := s.root()
+	if err != nil {
+		return BlogPost{}, parseError(fmt.Errorf("parsing item: %w", err))
+	}
+	item, err := decodeItem(s.d, &start, o.keepRawXML)
+	if err != nil {
+		return BlogPost{}, parseError(fmt.Errorf("parsing item: %w", err))
+	}
+
+	// nothing says whether it's atom 0.3, but its date names can't mean
+	// anything else
+	post, _ := itemPost(atom03Dates(item), "", "", "https", o)
+	return post, nil
+}
+
+// ParseFeed is ParseFeedFunc collecting every post into a slice.
+func ParseFeed(r io.Reader, opts ...Option) ([]BlogPost, error) {
+	_, posts, err := parseFeedPosts(r, newOptions(opts))
+	return posts, err
+}
+
+// ParseFeedInfo is ParseFeed that also hands back the feed-level metadata.
+func ParseFeedInfo(r io.Reader, opts ...Option) (FeedInfo, []BlogPost, error) {
+	return parseFeedPosts(r, newOptions(opts))
+}
+
+func parseFeedPosts(r io.Reader, o *options) (FeedInfo, []BlogPost, error) {
+	var posts []BlogPost
+	info, err := parseFeed(r, o, func(post BlogPost) error {
+		posts = append(posts, post)
+		return nil
+	})
+	if errors.Is(err, ErrNoItemsFound) {
+		return info, nil, err
+	}
+	if err != nil {
+		return FeedInfo{}, nil, err
+	}
+
+	return info, posts, nil
+}
+
+// ParseFeeds is the offline FetchAllFeeds: docs maps each feed URL to its
+// raw body. Posts get merged, deduped & sorted the same way, and each
+// document that fails to parse shows up in the error map under its URL.
+func ParseFeeds(docs map[string][]byte, opts ...Option) ([]BlogPost, map[string]error) {
+	o := newOptions(opts)
+
+	// map order is random, go through it sorted so dedup picks the same winner every time
+	urls := make([]string, 0, len(docs))
+	for url := range docs {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	var groups [][]BlogPost
+	errs := make(map[string]error)
+	for _, url := range urls {
+		_, feedPosts, err := parseFeedPosts(bytes.NewReader(docs[url]), o.fetchedFrom(url))
+		if err != nil {
+			errs[url] = err
+			continue
+		}
+
+		tagSource(feedPosts, url)
+		groups = append(groups, feedPosts)
+	}
+
+	capSources(groups, o)
+	return mergePosts(flatten(groups), o), errs
+}
@@ -0,0 +1,798 @@
+package feed
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+// anything the date parser does should be over long before this, even on
+// the slowest CI box. a regexp or layout going quadratic blows way past it.
+const maxDateParseTime = 50 * time.Millisecond
+
+// FuzzParseDateString throws garbage at the date parser: it must never
+// panic, never take long, and never come up with something silly for a
+// relative date. seeds in testdata/fuzz/FuzzParseDateString.
+func FuzzParseDateString(f *testing.F) {
+	f.Add("Mon, 02 Jan 2006 15:04:05 -0700")
+	f.Add("2006-01-02T15:04:05Z")
+	f.Add("2 days ago")
+	f.Add(strings.Repeat("9", maxDateLength+1))
+
+	ref := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		ny = time.FixedZone("EST", -5*60*60)
+	}
+	optionSets := []*options{
+		newOptions(nil),
+		newOptions([]Option{WithRelativeDates(), WithClock(func() time.Time { return ref })}),
+		newOptions([]Option{WithDefaultTimezone(ny)}),
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		for _, o := range optionSets {
+			start := time.Now()
+			parsed, ok := parseDateString(input, o)
+			if elapsed := time.Since(start); elapsed > maxDateParseTime {
+				t.Fatalf("parseDateString(%q) took %v", input, elapsed)
+			}
+			if !ok && !parsed.IsZero() {
+				t.Fatalf("parseDateString(%q) failed but returned %v", input, parsed)
+			}
+			if ok && o.relativeDates && parsed.After(ref) && isRelative(input) {
+				t.Fatalf("relative date %q came out in the future: %v", input, parsed)
+			}
+
+			// the same thing through every date field of an item
+			item := Item{PubDate: input, Date: input, Published: input, Updated: input}
+			start = time.Now()
+			parseDate(item, o)
+			parseUpdated(item, parsed, o)
+			if elapsed := time.Since(start); elapsed > 4*maxDateParseTime {
+				t.Fatalf("parseDate on %q took %v", input, elapsed)
+			}
+		}
+	})
+}
+
+// isRelative is whether input went down the relative date path rather than
+// matching a real layout
+func isRelative(input string) bool {
+	for _, format := range dateFormats {
+		if _, err := time.Parse(format, strings.TrimSpace(input)); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// BenchmarkParseMetadataOnly is the table-of-contents case: WithMetadataOnly
+// should skip cleaning summaries entirely, which is most of a full parse
+func BenchmarkParseMetadataOnly(b *testing.B) {
+	body := largeFeed(200)
+	modes := []struct {
+		name string
+		opts []Option
+	}{
+		{"full", nil},
+		{"metadata only", []Option{WithMetadataOnly()}},
+	}
+	for _, m := range modes {
+		b.Run(m.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				posts, err := ParseFeed(bytes.NewReader(body), m.opts...)
+				if err != nil {
+					b.Fatal(err)
+				}
+				// no summary means the cleaning never ran
+				if metadataOnly := len(m.opts) > 0; (posts[0].Summary == "") != metadataOnly {
+					b.Fatalf("summary %q with metadata only %v", posts[0].Summary, metadataOnly)
+				}
+			}
+		})
+	}
+}
+
+func TestDatePriority(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	jan := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		item string
+		opts []Option
+		want time.Time
+	}{
+		{"bogus pubDate, good updated", `<pubDate>sometime last week</pubDate><updated>2024-02-01T10:00:00Z</updated>`, nil, feb},
+		{"pubDate first by default", `<pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate><updated>2024-02-01T10:00:00Z</updated>`, nil, jan},
+		{"updated first", `<pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate><updated>2024-02-01T10:00:00Z</updated>`, []Option{WithDatePriority(DateUpdated, DatePubDate)}, feb},
+		{"only pubDate looked at", `<updated>2024-02-01T10:00:00Z</updated>`, []Option{WithDatePriority(DatePubDate)}, now},
+		{"latest", `<pubDate>Thu, 01 Feb 2024 10:00:00 GMT</pubDate><date>2024-01-01T10:00:00Z</date>`, []Option{WithLatestDate()}, feb},
+		{"latest skips garbage", `<pubDate>garbage</pubDate><date>2024-01-01T10:00:00Z</date><published>also garbage</published>`, []Option{WithLatestDate()}, jan},
+		{"nothing parses", `<pubDate>garbage</pubDate><updated>more garbage</updated>`, nil, now},
+	}
+	for _, tt := range tests {
+		body := `<rss version="2.0"><channel><title>Dates</title><item><title>Post</title><link>https://example.com/1</link>` + tt.item + `</item></channel></rss>`
+		opts := append([]Option{WithClock(func() time.Time { return now })}, tt.opts...)
+		posts, err := ParseFeed(strings.NewReader(body), opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := posts[0].PublishedAt; !got.Equal(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMinSummaryLength(t *testing.T) {
+	tests := []struct {
+		name string
+		item string
+		opts []Option
+		want string
+	}{
+		{"a dot is a summary by default", `<description>.</description>`, nil, "."},
+		{"a dot is too short", `<description>.</description>`, []Option{WithMinSummaryLength(10)}, "Visit post for details."},
+		{"on to the next candidate", `<description>.</description><content:encoded><![CDATA[<p>The actual post, with words.</p>]]></content:encoded>`, []Option{WithMinSummaryLength(10)}, "The actual post, with words."},
+		{"long enough", `<description>Twelve chars</description>`, []Option{WithMinSummaryLength(12)}, "Twelve chars"},
+		{"counts runes", `<description>ééééé</description>`, []Option{WithMinSummaryLength(5)}, "ééééé"},
+		{"custom fallback", `<description> - </description>`, []Option{WithMinSummaryLength(3), WithFallbackSummary("")}, ""},
+	}
+	for _, tt := range tests {
+		body := `<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/"><channel><title>Blog</title>
+<item><title>Post</title><link>https://example.com/1</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate>` + tt.item + `</item></channel></rss>`
+		posts, err := ParseFeed(strings.NewReader(body), tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if posts[0].Summary != tt.want {
+			t.Errorf("%s: summary %q, want %q", tt.name, posts[0].Summary, tt.want)
+		}
+	}
+}
+
+func TestContentLength(t *testing.T) {
+	tests := []struct {
+		name string
+		item string
+		opts []Option
+		want int
+	}{
+		// "Hello, wörld." cleaned is 13 runes, whatever the markup around it
+		{"content:encoded", `<description>short</description><content:encoded><![CDATA[<p>Hello, <b>wörld</b>.</p>]]></content:encoded>`, nil, 13},
+		{"atom content", `<content type="html">&lt;p&gt;Hello, &lt;i&gt;wörld&lt;/i&gt;.&lt;/p&gt;</content>`, nil, 13},
+		{"description only", `<description>&lt;p&gt;Hello, wörld.&lt;/p&gt;</description>`, nil, 13},
+		{"not cut by the summary length", `<content:encoded>` + strings.Repeat("word ", 200) + `</content:encoded>`, []Option{WithSummaryLength(20)}, 999},
+		{"metadata only", `<content:encoded><![CDATA[<p>Hello, <b>wörld</b>.</p>]]></content:encoded>`, []Option{WithMetadataOnly()}, 0},
+		{"nothing", ``, nil, 0},
+	}
+	for _, tt := range tests {
+		body := `<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/"><channel><title>Blog</title>
+<item><title>Post</title><link>https://example.com/1</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate>` + tt.item + `</item></channel></rss>`
+		posts, err := ParseFeed(strings.NewReader(body), tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if posts[0].ContentLength != tt.want {
+			t.Errorf("%s: ContentLength %d, want %d", tt.name, posts[0].ContentLength, tt.want)
+		}
+	}
+}
+
+func TestEstimatedUpdateInterval(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(offsets ...time.Duration) []time.Time {
+		var dates []time.Time
+		for _, offset := range offsets {
+			dates = append(dates, start.Add(offset))
+		}
+		return dates
+	}
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name  string
+		dates []time.Time
+		want  time.Duration
+	}{
+		{"daily", at(0, day, 2*day, 3*day, 4*day), day},
+		{"daily, shuffled", at(3*day, 0, 4*day, day, 2*day), day},
+		// gaps of 1h, 2h, 1h, 30 days: the median shrugs off the one long break
+		{"uneven", at(0, time.Hour, 3*time.Hour, 4*time.Hour, 4*time.Hour+30*day), 90 * time.Minute},
+		{"odd gap count", at(0, time.Hour, 3*time.Hour, 10*time.Hour), 2 * time.Hour},
+		{"too few", at(0, day), 0},
+		{"none", nil, 0},
+	}
+	for _, tt := range tests {
+		if got := medianInterval(tt.dates); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEstimatedUpdateIntervalFromFeed(t *testing.T) {
+	body := `<rss version="2.0"><channel><title>Weekly</title>
+<item><title>Three</title><link>https://example.com/3</link><pubDate>Mon, 15 Jan 2024 10:00:00 GMT</pubDate></item>
+<item><title>Undated</title><link>https://example.com/u</link></item>
+<item><title>Two</title><link>https://example.com/2</link><pubDate>Mon, 08 Jan 2024 10:00:00 GMT</pubDate></item>
+<item><title>One</title><link>https://example.com/1</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate></item>
+</channel></rss>`
+	info, _, err := ParseFeedInfo(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the undated post's fetch time doesn't count
+	if want := 7 * 24 * time.Hour; info.EstimatedUpdateInterval != want {
+		t.Errorf("got %v, want %v", info.EstimatedUpdateInterval, want)
+	}
+}
+
+func TestMaxTitleLength(t *testing.T) {
+	const long = "This is an absurdly long title that is really a whole sentence about the post"
+	tests := []struct {
+		name  string
+		title string
+		opts  []Option
+		want  string
+	}{
+		{"no limit by default", long, nil, long},
+		{"word boundary", long, []Option{WithMaxTitleLength(30)}, "This is an absurdly long title..."},
+		{"custom ellipsis", long, []Option{WithMaxTitleLength(30), WithEllipsis("…")}, "This is an absurdly long title…"},
+		{"strict cap", long, []Option{WithMaxTitleLength(30), WithStrictTruncation()}, "This is an absurdly long..."},
+		{"short enough", "Short title", []Option{WithMaxTitleLength(30)}, "Short title"},
+		{"multi-byte", "Ünïcödé ëvërywhërë ïn thïs tïtlë", []Option{WithMaxTitleLength(20)}, "Ünïcödé ëvërywhërë..."},
+	}
+	for _, tt := range tests {
+		body := `<rss version="2.0"><channel><title>Blog</title><item><title>` + tt.title +
+			`</title><link>https://example.com/1</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate></item></channel></rss>`
+		posts, err := ParseFeed(strings.NewReader(body), tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if posts[0].Title != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, posts[0].Title, tt.want)
+		}
+	}
+}
+
+func TestParseItem(t *testing.T) {
+	tests := []struct {
+		name string
+		item string
+		want BlogPost
+	}{
+		{"rss item", `<item><title>Hello &amp; welcome</title><link>https://example.com/hello</link>
+<guid isPermaLink="false">post-1</guid><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate>
+<author>alice@example.com (Alice)</author><description>&lt;p&gt;The &lt;b&gt;first&lt;/b&gt; post.&lt;/p&gt;</description></item>`,
+			BlogPost{Title: "Hello & welcome", Link: "https://example.com/hello", GUID: "post-1", Author: "Alice", Summary: "The first post.",
+				PublishedAt: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}},
+		{"atom entry", `<?xml version="1.0"?>
+<entry xmlns="http://www.w3.org/2005/Atom"><title>Pushed</title><id>urn:uuid:1234</id>
+<link rel="alternate" href="https://example.com/pushed"/><published>2024-02-03T04:05:06Z</published>
+<author><name>Bob</name></author><summary>Delivered by a hub.</summary></entry>`,
+			BlogPost{Title: "Pushed", Link: "https://example.com/pushed", GUID: "urn:uuid:1234", Author: "Bob", Summary: "Delivered by a hub.",
+				PublishedAt: time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)}},
+		{"atom 0.3 entry", `<entry xmlns="http://purl.org/atom/ns#"><title>Old</title><id>tag:example.com,2004:1</id>
+<link rel="alternate" href="https://example.com/old"/><issued>2004-05-06T07:08:09Z</issued><author><name>Carol</name></author></entry>`,
+			BlogPost{Title: "Old", Link: "https://example.com/old", GUID: "tag:example.com,2004:1", Author: "Carol", Summary: "Visit post for details.",
+				PublishedAt: time.Date(2004, 5, 6, 7, 8, 9, 0, time.UTC)}},
+	}
+	for _, tt := range tests {
+		post, err := ParseItem(strings.NewReader(tt.item))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if post.Title != tt.want.Title || post.Link != tt.want.Link || post.GUID != tt.want.GUID ||
+			post.Author != tt.want.Author || post.Summary != tt.want.Summary || !post.PublishedAt.Equal(tt.want.PublishedAt) {
+			t.Errorf("%s: got %q %q %q %q %q %v, want %q %q %q %q %q %v", tt.name,
+				post.Title, post.Link, post.GUID, post.Author, post.Summary, post.PublishedAt,
+				tt.want.Title, tt.want.Link, tt.want.GUID, tt.want.Author, tt.want.Summary, tt.want.PublishedAt)
+		}
+		if !post.Date.Equal(post.PublishedAt) {
+			t.Errorf("%s: Date %v isn't PublishedAt", tt.name, post.Date)
+		}
+	}
+
+	for _, bad := range []string{"", "   ", "<item><title>cut off"} {
+		if _, err := ParseItem(strings.NewReader(bad)); err == nil {
+			t.Errorf("no error parsing %q", bad)
+		}
+	}
+}
+
+func TestDefaultTimezone(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		date string
+		loc  *time.Location
+		want time.Time
+	}{
+		{"2024-03-01 09:30:00", nil, time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)},
+		{"2024-03-01 09:30:00", tokyo, time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC)},
+		{"2024-03-01T09:30:00", tokyo, time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC)},
+		{"2024-03-01", tokyo, time.Date(2024, 2, 29, 15, 0, 0, 0, time.UTC)},
+		{"March 1, 2024", tokyo, time.Date(2024, 2, 29, 15, 0, 0, 0, time.UTC)},
+		// anything that says its zone keeps it
+		{"2024-03-01T09:30:00Z", tokyo, time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)},
+		{"2024-03-01T09:30:00-05:00", tokyo, time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC)},
+		{"Fri, 01 Mar 2024 09:30:00 GMT", tokyo, time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)},
+		{"Fri, 01 Mar 2024 09:30:00 +0100", tokyo, time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, ok := parseDateString(tt.date, newOptions([]Option{WithDefaultTimezone(tt.loc)}))
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("%q in %v: got %v (%v), want %v", tt.date, tt.loc, got, ok, tt.want)
+		}
+	}
+
+	// 09:00 in tokyo is before 08:00 UTC, which flips the order
+	body := `<rss version="2.0"><channel><title>Blog</title>
+<item><title>local</title><link>https://example.com/1</link><pubDate>2024-03-01 09:00:00</pubDate></item>
+<item><title>utc</title><link>https://example.com/2</link><pubDate>Fri, 01 Mar 2024 08:00:00 GMT</pubDate></item>
+</channel></rss>`
+	srv := feedtest.NewServer([]byte(body))
+	defer srv.Close()
+	for _, tc := range []struct {
+		opts  []Option
+		first string
+	}{
+		{nil, "local"},
+		{[]Option{WithDefaultTimezone(tokyo)}, "utc"},
+	} {
+		if posts := FetchAllFeeds([]string{srv.URL}, tc.opts...); len(posts) != 2 || posts[0].Title != tc.first {
+			t.Errorf("with %d options: got %v, want %q first", len(tc.opts), posts, tc.first)
+		}
+	}
+}
+
+// parseItemXML parses item inside a bare RSS channel & hands back its post
+func parseItemXML(t *testing.T, item string, opts ...Option) BlogPost {
+	t.Helper()
+	posts, err := ParseFeed(strings.NewReader(`<rss version="2.0"><channel><title>Blog</title><link>https://example.com/</link>`+item+`</channel></rss>`), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("got %d posts, want 1", len(posts))
+	}
+	return posts[0]
+}
+
+func TestEnclosures(t *testing.T) {
+	tests := []struct {
+		name      string
+		enclosure string
+		want      []Enclosure
+	}{
+		{"valid", `<enclosure url="https://example.com/ep1.mp3" type="audio/mpeg" length="12345678"/>`,
+			[]Enclosure{{URL: "https://example.com/ep1.mp3", Type: "audio/mpeg", Length: 12345678}}},
+		{"no length", `<enclosure url="https://example.com/ep1.mp3" type="audio/mpeg"/>`,
+			[]Enclosure{{URL: "https://example.com/ep1.mp3", Type: "audio/mpeg"}}},
+		{"unknown length", `<enclosure url="https://example.com/ep1.mp3" type="audio/mpeg" length="unknown"/>`,
+			[]Enclosure{{URL: "https://example.com/ep1.mp3", Type: "audio/mpeg"}}},
+		{"negative length", `<enclosure url="https://example.com/ep1.mp3" length="-1"/>`,
+			[]Enclosure{{URL: "https://example.com/ep1.mp3"}}},
+		{"no url", `<enclosure type="audio/mpeg" length="100"/>`, nil},
+		{"two", `<enclosure url=" https://example.com/a.mp3 " type="audio/mpeg" length=" 10 "/><enclosure url="https://example.com/a.ogg" type="audio/ogg" length="20"/>`,
+			[]Enclosure{{URL: "https://example.com/a.mp3", Type: "audio/mpeg", Length: 10}, {URL: "https://example.com/a.ogg", Type: "audio/ogg", Length: 20}}},
+	}
+	for _, tt := range tests {
+		post := parseItemXML(t, `<item><title>Episode</title><link>https://example.com/ep1</link>`+tt.enclosure+`</item>`)
+		if !reflect.DeepEqual(post.Enclosures, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, post.Enclosures, tt.want)
+		}
+	}
+}
+
+func TestSelfAndHubLinks(t *testing.T) {
+	tests := []struct {
+		name      string
+		links     string
+		self, hub string
+	}{
+		{"both", `<atom:link rel="self" type="application/rss+xml" href="https://example.com/feed.xml"/>
+<atom:link rel="hub" href="https://pubsubhubbub.example.org/"/>`, "https://example.com/feed.xml", "https://pubsubhubbub.example.org/"},
+		{"first of each wins", `<atom:link rel="hub" href="https://hub1.example.org/"/><atom:link rel="hub" href="https://hub2.example.org/"/>
+<atom:link rel="self" href="https://example.com/feed.xml"/><atom:link rel="self" href="https://example.com/other.xml"/>`, "https://example.com/feed.xml", "https://hub1.example.org/"},
+		{"self only", `<atom:link rel="self" href="https://example.com/feed.xml"/>`, "https://example.com/feed.xml", ""},
+		{"neither", ``, "", ""},
+	}
+	for _, tt := range tests {
+		body := `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>Blog</title><link>https://example.com/</link>` + tt.links + `
+<item><title>Post</title><link>https://example.com/1</link></item></channel></rss>`
+		info, _, err := ParseFeedInfo(strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if info.SelfURL != tt.self || info.HubURL != tt.hub {
+			t.Errorf("%s: self %q, hub %q, want %q, %q", tt.name, info.SelfURL, info.HubURL, tt.self, tt.hub)
+		}
+		// the atom links mustn't push out the RSS <link>
+		if info.Link != "https://example.com/" {
+			t.Errorf("%s: Link %q", tt.name, info.Link)
+		}
+	}
+}
+
+func TestFeedAndItemCategories(t *testing.T) {
+	body := `<rss version="2.0"><channel><title>Blog</title><link>https://example.com/</link>
+<category>Tech</category><category>Programming</category>
+<item><title>Post</title><link>https://example.com/1</link><category>Go</category><category>Tech</category></item>
+<item><title>Untagged</title><link>https://example.com/2</link></item>
+</channel></rss>`
+	info, posts, err := ParseFeedInfo(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Tag{{Label: "Tech"}, {Label: "Programming"}}; !reflect.DeepEqual(info.Categories, want) {
+		t.Errorf("feed categories %+v, want %+v", info.Categories, want)
+	}
+	// the feed's categories stay on the feed & never leak into a post's tags
+	if want := []Tag{{Label: "Go"}, {Label: "Tech"}}; !reflect.DeepEqual(posts[0].Tags, want) {
+		t.Errorf("post tags %+v, want %+v", posts[0].Tags, want)
+	}
+	if posts[1].Tags != nil {
+		t.Errorf("untagged post got tags %+v", posts[1].Tags)
+	}
+}
+
+func TestChannelDates(t *testing.T) {
+	tests := []struct {
+		name           string
+		dates          string
+		pub, lastBuild time.Time
+	}{
+		{"both", `<pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate><lastBuildDate>Tue, 02 Jan 2024 11:30:00 +0100</lastBuildDate>`,
+			time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 10, 30, 0, 0, time.UTC)},
+		{"padded & iso", `<pubDate>
+	2024-01-01T10:00:00Z
+</pubDate>`, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), time.Time{}},
+		{"junk stays zero", `<pubDate>sometime last week</pubDate><lastBuildDate></lastBuildDate>`, time.Time{}, time.Time{}},
+	}
+	for _, tt := range tests {
+		body := `<rss version="2.0"><channel><title>Blog</title>` + tt.dates + `
+<item><title>Post</title><link>https://example.com/1</link><pubDate>Wed, 03 Jan 2024 10:00:00 GMT</pubDate></item></channel></rss>`
+		info, _, err := ParseFeedInfo(strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !info.PubDate.Equal(tt.pub) || !info.LastBuildDate.Equal(tt.lastBuild) {
+			t.Errorf("%s: got %v & %v, want %v & %v", tt.name, info.PubDate, info.LastBuildDate, tt.pub, tt.lastBuild)
+		}
+	}
+}
+
+func TestByteOrderMark(t *testing.T) {
+	const bom = "\xEF\xBB\xBF"
+	feeds := map[string]string{
+		"rss":              bom + `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0"><channel><title>Windows</title><item><title>Post</title><link>https://example.com/1</link></item></channel></rss>`,
+		"no declaration":   bom + `<rss version="2.0"><channel><title>Windows</title><item><title>Post</title><link>https://example.com/1</link></item></channel></rss>`,
+		"atom":             bom + `<feed xmlns="http://www.w3.org/2005/Atom"><title>Windows</title><entry><title>Post</title><id>urn:1</id><link href="https://example.com/1"/></entry></feed>`,
+		"leading newlines": bom + "\r\n\r\n" + `<rss version="2.0"><channel><title>Windows</title><item><title>Post</title><link>https://example.com/1</link></item></channel></rss>`,
+	}
+	for name, body := range feeds {
+		// WithLenient reads the whole body first, which is its own path
+		for _, opts := range [][]Option{nil, {WithLenient()}} {
+			posts, err := ParseFeed(strings.NewReader(body), opts...)
+			if err != nil {
+				t.Errorf("%s (%d options): %v", name, len(opts), err)
+				continue
+			}
+			if len(posts) != 1 || posts[0].Title != "Post" || posts[0].SourceTitle != "Windows" {
+				t.Errorf("%s (%d options): got %+v", name, len(opts), posts)
+			}
+		}
+
+		srv := feedtest.NewServer([]byte(body))
+		if _, err := FetchFeed(srv.URL); err != nil {
+			t.Errorf("%s: fetching: %v", name, err)
+		}
+		srv.Close()
+	}
+}
+
+func TestCategoryDomains(t *testing.T) {
+	tests := []struct {
+		name       string
+		categories string
+		want       []Tag
+	}{
+		{"domain", `<category domain="https://example.com/topics">Go</category>`, []Tag{{Label: "Go", Domain: "https://example.com/topics"}}},
+		{"comma separated", `<category>go, rust,linux ,</category>`, []Tag{{Label: "go"}, {Label: "rust"}, {Label: "linux"}}},
+		{"split keeps the domain", `<category domain="tags">go, rust</category>`, []Tag{{Label: "go", Domain: "tags"}, {Label: "rust", Domain: "tags"}}},
+		{"same label, two taxonomies", `<category domain="section">News</category><category domain="tag">News</category><category domain="tag">News</category>`,
+			[]Tag{{Label: "News", Domain: "section"}, {Label: "News", Domain: "tag"}}},
+		{"padded", `<category domain=" tags "> Go </category>`, []Tag{{Label: "Go", Domain: "tags"}}},
+		{"empty", `<category domain="tags"> </category>`, nil},
+	}
+	for _, tt := range tests {
+		post := parseItemXML(t, `<item><title>Post</title><link>https://example.com/1</link>`+tt.categories+`</item>`)
+		if !reflect.DeepEqual(post.Tags, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, post.Tags, tt.want)
+		}
+	}
+
+	// atom calls the domain a scheme
+	posts, err := ParseFeed(strings.NewReader(`<feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title>
+<entry><title>Post</title><id>urn:1</id><link href="https://example.com/1"/><category term="go" scheme="https://example.com/tags"/></entry></feed>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Tag{{Label: "go", Domain: "https://example.com/tags"}}; !reflect.DeepEqual(posts[0].Tags, want) {
+		t.Errorf("atom: got %+v, want %+v", posts[0].Tags, want)
+	}
+}
+
+const (
+	wordpressFeed = `<rss version="2.0"><channel><title>WP Blog</title><link>https://wp.example.com/</link>
+<generator>https://wordpress.org/?v=6.4.2</generator>
+<item><title>Post</title><link>https://wp.example.com/1</link><description>The opening lines of the post [&#8230;]</description></item>
+</channel></rss>`
+	hugoAtomFeed = `<feed xmlns="http://www.w3.org/2005/Atom"><title>Hugo Blog</title>
+<generator uri="https://gohugo.io/" version="0.121.1">Hugo</generator>
+<entry><title>Post</title><id>urn:1</id><link href="https://hugo.example.com/1"/><summary>The opening lines of the post [&#8230;]</summary></entry>
+</feed>`
+)
+
+func TestGenerator(t *testing.T) {
+	tests := []struct {
+		name      string
+		feed      string
+		opts      []Option
+		generator string
+		summary   string
+	}{
+		{"wordpress", wordpressFeed, nil, "https://wordpress.org/?v=6.4.2", "The opening lines of the post …"},
+		{"wordpress with quirks", wordpressFeed, []Option{WithGeneratorQuirks()}, "https://wordpress.org/?v=6.4.2", "The opening lines of the post"},
+		{"atom", hugoAtomFeed, nil, "Hugo 0.121.1", "The opening lines of the post …"},
+		// quirks are for the generators that need them
+		{"atom with quirks", hugoAtomFeed, []Option{WithGeneratorQuirks()}, "Hugo 0.121.1", "The opening lines of the post …"},
+		{"version already in the name", strings.Replace(hugoAtomFeed, ">Hugo<", ">Hugo 0.121.1<", 1), nil, "Hugo 0.121.1", "The opening lines of the post …"},
+	}
+	for _, tt := range tests {
+		info, posts, err := ParseFeedInfo(strings.NewReader(tt.feed), tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if info.Generator != tt.generator {
+			t.Errorf("%s: generator %q, want %q", tt.name, info.Generator, tt.generator)
+		}
+		if posts[0].Summary != tt.summary {
+			t.Errorf("%s: summary %q, want %q", tt.name, posts[0].Summary, tt.summary)
+		}
+	}
+}
+
+func TestLinkSelection(t *testing.T) {
+	tests := []struct {
+		name  string
+		links string
+		want  string
+	}{
+		{"alternate among the rest", `<link rel="enclosure" type="audio/mpeg" href="https://example.com/ep.mp3"/>
+<link rel="related" href="https://elsewhere.example.com/"/><link rel="self" href="https://example.com/entry.atom"/>
+<link rel="replies" href="https://example.com/1/comments"/><link rel="alternate" type="text/html" href="https://example.com/1"/>`, "https://example.com/1"},
+		{"html alternate beats other ones", `<link rel="alternate" type="application/json" href="https://example.com/1.json"/><link rel="alternate" href="https://example.com/1"/>`, "https://example.com/1"},
+		{"rel-less beats a non-html alternate", `<link rel="alternate" type="application/pdf" href="https://example.com/1.pdf"/><link href="https://example.com/1"/>`, "https://example.com/1"},
+		{"non-html alternate over nothing", `<link rel="enclosure" href="https://example.com/ep.mp3"/><link rel="alternate" type="application/pdf" href="https://example.com/1.pdf"/>`, "https://example.com/1.pdf"},
+		{"rel case & spacing", `<link rel="related" href="https://elsewhere.example.com/"/><link rel=" Alternate " href="https://example.com/1"/>`, "https://example.com/1"},
+		{"only an enclosure", `<link rel="enclosure" href="https://example.com/ep.mp3"/>`, ""},
+	}
+	for _, tt := range tests {
+		posts, err := ParseFeed(strings.NewReader(`<feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title>
+<entry><title>Post</title><id>urn:1</id>` + tt.links + `</entry></feed>`))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if posts[0].Link != tt.want {
+			t.Errorf("%s: link %q, want %q", tt.name, posts[0].Link, tt.want)
+		}
+	}
+}
+
+func TestFeedVersion(t *testing.T) {
+	tests := []struct {
+		name            string
+		feed            string
+		format, version string
+	}{
+		{"rss 0.91", `<?xml version="1.0"?>
+<!DOCTYPE rss PUBLIC "-//Netscape Communications//DTD RSS 0.91//EN" "http://my.netscape.com/publish/formats/rss-0.91.dtd">
+<rss version="0.91"><channel><title>Old Blog</title><link>https://old.example.com/</link><language>en-us</language>
+<item><title>Post</title><link>https://old.example.com/1</link><description>An old post.</description></item></channel></rss>`, "rss", "0.91"},
+		{"rss 2.0", `<rss version="2.0"><channel><title>Blog</title><link>https://example.com/</link>
+<item><title>Post</title><link>https://example.com/1</link><guid>https://example.com/1</guid><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate></item></channel></rss>`, "rss", "2.0"},
+		{"rss, padded", `<rss version=" 2.0 "><channel><title>Blog</title><item><title>Post</title><link>https://example.com/1</link></item></channel></rss>`, "rss", "2.0"},
+		{"atom 1.0", `<feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title><entry><title>Post</title><id>urn:1</id><link href="https://example.com/1"/></entry></feed>`, "atom", "1.0"},
+		{"atom 0.3", `<feed version="0.3" xmlns="http://purl.org/atom/ns#"><title>Blog</title><entry><title>Post</title><id>urn:1</id><link rel="alternate" href="https://example.com/1"/></entry></feed>`, "atom", "0.3"},
+		{"rdf", `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"><channel><title>Blog</title></channel>
+<item><title>Post</title><link>https://example.com/1</link></item></rdf:RDF>`, "rdf", "1.0"},
+	}
+	for _, tt := range tests {
+		info, posts, err := ParseFeedInfo(strings.NewReader(tt.feed))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if info.Format != tt.format || info.Version != tt.version {
+			t.Errorf("%s: got %q %q, want %q %q", tt.name, info.Format, info.Version, tt.format, tt.version)
+		}
+		if len(posts) != 1 || posts[0].Link == "" {
+			t.Errorf("%s: got posts %+v", tt.name, posts)
+		}
+	}
+}
+
+func TestPermalinkGUID(t *testing.T) {
+	tests := []struct {
+		name string
+		item string
+		link string
+		guid string
+	}{
+		{"permalink", `<guid isPermaLink="true">https://example.com/1</guid>`, "https://example.com/1", "https://example.com/1"},
+		{"permalink by default", `<guid> https://example.com/1 </guid>`, "https://example.com/1", "https://example.com/1"},
+		{"flag case", `<guid isPermaLink="TRUE">https://example.com/1</guid>`, "https://example.com/1", "https://example.com/1"},
+		{"not a permalink", `<guid isPermaLink="false">https://example.com/1</guid>`, "", "https://example.com/1"},
+		{"not a url", `<guid>post-1</guid>`, "", "post-1"},
+		{"link wins", `<link>https://example.com/article</link><guid>https://example.com/?p=1</guid>`, "https://example.com/article", "https://example.com/?p=1"},
+	}
+	for _, tt := range tests {
+		post := parseItemXML(t, `<item><title>Post</title>`+tt.item+`</item>`)
+		if post.Link != tt.link || post.GUID != tt.guid {
+			t.Errorf("%s: link %q, guid %q, want %q, %q", tt.name, post.Link, post.GUID, tt.link, tt.guid)
+		}
+	}
+}
+
+func TestTitleNormalization(t *testing.T) {
+	const title = "<![CDATA[\n\t\tBreaking:   a title\n\t\tover  two lines\n\t]]>"
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"raw by default", nil, "\n\t\tBreaking:   a title\n\t\tover  two lines\n\t"},
+		{"normalized", []Option{WithTitleNormalization()}, "Breaking: a title over two lines"},
+		{"normalized before truncating", []Option{WithTitleNormalization(), WithMaxTitleLength(20)}, "Breaking: a title..."},
+	}
+	for _, tt := range tests {
+		post := parseItemXML(t, `<item><title>`+title+`</title><link>https://example.com/1</link></item>`, tt.opts...)
+		if post.Title != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, post.Title, tt.want)
+		}
+	}
+}
+
+func TestFeedUpdated(t *testing.T) {
+	want := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		feed string
+		want time.Time
+	}{
+		{"atom", `<feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title><updated>2024-03-01T13:00:00+01:00</updated>
+<entry><title>Post</title><id>urn:1</id><link href="https://example.com/1"/><updated>2024-02-01T10:00:00Z</updated></entry></feed>`, want},
+		{"atom 0.3", `<feed version="0.3" xmlns="http://purl.org/atom/ns#"><title>Blog</title><modified>2024-03-01T12:00:00Z</modified>
+<entry><title>Post</title><id>urn:1</id><link rel="alternate" href="https://example.com/1"/></entry></feed>`, want},
+		{"rss with atom:updated", `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>Blog</title><atom:updated>2024-03-01T12:00:00Z</atom:updated>
+<item><title>Post</title><link>https://example.com/1</link></item></channel></rss>`, want},
+		// an entry's own updated is not the feed's
+		{"only the entry's", `<feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title>
+<entry><title>Post</title><id>urn:1</id><link href="https://example.com/1"/><updated>2024-02-01T10:00:00Z</updated></entry></feed>`, time.Time{}},
+	}
+	for _, tt := range tests {
+		info, _, err := ParseFeedInfo(strings.NewReader(tt.feed))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !info.FeedUpdated.Equal(tt.want) {
+			t.Errorf("%s: FeedUpdated %v, want %v", tt.name, info.FeedUpdated, tt.want)
+		}
+	}
+}
+
+func TestMultipleChannels(t *testing.T) {
+	const doc = `<rss version="2.0">
+<channel><title>First Blog</title><link>https://first.example.com/</link>
+<item><title>One</title><link>https://first.example.com/1</link></item>
+<item><title>Two</title><link>https://first.example.com/2</link></item>
+</channel>
+<channel><title> Second Blog </title><link>https://second.example.com/</link>
+<item><title>Three</title><link>https://second.example.com/3</link></item>
+</channel>
+<channel><link>https://third.example.com/</link>
+<item><title>Four</title><link>https://third.example.com/4</link></item>
+</channel>
+</rss>`
+
+	info, posts, err := ParseFeedInfo(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the feed itself is still the first channel
+	if info.Title != "First Blog" || info.Link != "https://first.example.com/" {
+		t.Errorf("feed is %q at %q", info.Title, info.Link)
+	}
+
+	var got []string
+	for _, post := range posts {
+		got = append(got, post.Title+" from "+post.SourceTitle)
+	}
+	// an untitled channel's posts fall back to the feed title
+	want := []string{"One from First Blog", "Two from First Blog", "Three from Second Blog", "Four from First Blog"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCommentFeed(t *testing.T) {
+	const doc = `<rss version="2.0" xmlns:wfw="http://wellformedweb.org/CommentAPI/" xmlns:slash="http://purl.org/rss/1.0/modules/slash/"><channel>
+<title>WP Blog</title><link>https://wp.example.com/</link><generator>https://wordpress.org/?v=6.4.2</generator>
+<item><title>Absolute</title><link>https://wp.example.com/2024/01/absolute/</link>
+<comments>https://wp.example.com/2024/01/absolute/#respond</comments>
+<wfw:commentRss>https://wp.example.com/2024/01/absolute/feed/</wfw:commentRss><slash:comments>3</slash:comments></item>
+<item><title>Relative</title><link>https://wp.example.com/2024/01/relative/</link>
+<wfw:commentRss> feed/ </wfw:commentRss></item>
+<item><title>Protocol-relative</title><link>https://wp.example.com/2024/01/cdn/</link>
+<wfw:commentRss>//comments.example.com/cdn/feed/</wfw:commentRss></item>
+<item><title>Someone else's commentRss</title><link>https://wp.example.com/2024/01/other/</link>
+<commentRss xmlns="http://example.com/not-wfw/">https://wp.example.com/ignored/</commentRss></item>
+<item><title>Comments closed</title><link>https://wp.example.com/2024/01/closed/</link></item>
+</channel></rss>`
+
+	posts, err := ParseFeed(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"https://wp.example.com/2024/01/absolute/feed/",
+		"https://wp.example.com/2024/01/relative/feed/",
+		"https://comments.example.com/cdn/feed/",
+		"",
+		"",
+	}
+	var got []string
+	for _, post := range posts {
+		got = append(got, post.CommentFeedURL)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestEnclosureLinks(t *testing.T) {
+	const enclosureOnly = `<item><title>Sunset</title><enclosure url=" " type="image/jpeg"/>
+<enclosure url="https://photos.example.com/sunset.jpg" length="20480" type="image/jpeg"/></item>`
+
+	tests := []struct {
+		name string
+		item string
+		opts []Option
+		want string
+	}{
+		{"off by default", enclosureOnly, nil, ""},
+		{"enclosure only", enclosureOnly, []Option{WithEnclosureLinks()}, "https://photos.example.com/sunset.jpg"},
+		{"protocol-relative enclosure", `<item><title>Clip</title><enclosure url="//cdn.example.com/clip.mp4" type="video/mp4"/></item>`,
+			[]Option{WithEnclosureLinks()}, "https://cdn.example.com/clip.mp4"},
+		// a real link always wins
+		{"has a link", `<item><title>Post</title><link>https://example.com/post</link><enclosure url="https://photos.example.com/a.jpg"/></item>`,
+			[]Option{WithEnclosureLinks()}, "https://example.com/post"},
+		{"permalink guid", `<item><title>Post</title><guid>https://example.com/guid</guid><enclosure url="https://photos.example.com/a.jpg"/></item>`,
+			[]Option{WithEnclosureLinks()}, "https://example.com/guid"},
+		{"nothing at all", `<item><title>Nothing</title></item>`, []Option{WithEnclosureLinks()}, ""},
+	}
+	for _, tt := range tests {
+		post := parseItemXML(t, tt.item, tt.opts...)
+		if post.Link != tt.want {
+			t.Errorf("%s: link %q, want %q", tt.name, post.Link, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+)
+
+// FetchPlan is what a fetch of one feed would send, worked out without
+// touching the network.
+type FetchPlan struct {
+	// URL is the feed's URL as the request would go out, normalized the
+	// way FetchAllFeeds does it (see normalizeFeedURL)
+	URL string
+	// Duplicate is true when an earlier feed in the list normalizes to the
+	// same URL, so this one shares its fetch instead of making its own
+	Duplicate bool
+	Method    string
+	// Header is every header harvest sets itself (WithHeader, conditional
+	// headers). the transport still adds its own, like User-Agent &
+	// Accept-Encoding, when the request actually goes out.
+	Header http.Header
+	// ETag & LastModified are the cached validators the request would send,
+	// empty when there's nothing cached
+	ETag         string
+	LastModified string
+	// HeadProbe is true when a cached copy would get a HEAD request first
+	HeadProbe bool
+	// Err is why the request couldn't be built at all, e.g. a malformed URL
+	Err error
+}
+
+// PlanFetch reports what FetchAllFeeds would request for each feed, in input
+// order, for checking auth headers, caching & dedup before a real run.
+// Every feed gets a plan, duplicates included, so plans[i] is feeds[i]'s.
+func PlanFetch(feeds []string, opts ...Option) []FetchPlan {
+	return NewFetcher(opts...).PlanFetch(feeds)
+}
+
+func (f *Fetcher) PlanFetch(feeds []string) []FetchPlan {
+	return f.PlanFetchWith(f.requests(feeds))
+}
+
+// PlanFetchWith is PlanFetch for FetchAllFeedsWith, each feed's own options included.
+func (f *Fetcher) PlanFetchWith(feeds []FeedRequest) []FetchPlan {
+	urls, first := fetchOrder(feeds)
+	plans := make([]FetchPlan, len(feeds))
+	for i, feed := range feeds {
+		plans[i] = f.plan(urls[i], f.optionsFor(feed.Options))
+		plans[i].Duplicate = first[i] != i
+	}
+	return plans
+}
+
+// plan follows fetchBody's lead on the cache, minus the actual requests.
+// no validators means unchanged won't bother probing either.
+func (f *Fetcher) plan(url string, o *options) FetchPlan {
+	p := FetchPlan{URL: url, Method: http.MethodGet}
+
+	entry, cached := f.cacheGet(url)
+	if o.forceRefresh {
+		entry, cached = CacheEntry{}, false
+	}
+	p.ETag, p.LastModified = entry.ETag, entry.LastModified
+	p.HeadProbe = cached && o.headProbe && entry.hasValidators()
+
+	req, err := f.newRequest(context.Background(), p.Method, url, entry, o)
+	if err != nil {
+		p.Err = err
+		return p
+	}
+	p.URL = req.URL.String()
+	p.Header = req.Header
+	return p
+}
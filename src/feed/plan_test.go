@@ -0,0 +1,105 @@
+package feed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+func TestPlanFetch(t *testing.T) {
+	store := newMemoryCache()
+	store.Set("https://example.com/etag.xml", CacheEntry{ETag: `"v1"`, Body: []byte("<rss/>")})
+	store.Set("https://example.com/dated.xml", CacheEntry{LastModified: "Mon, 01 Jan 2024 10:00:00 GMT", Body: []byte("<rss/>")})
+	store.Set("https://example.com/bare.xml", CacheEntry{Body: []byte("<rss/>")})
+
+	tests := []struct {
+		name         string
+		opts         []Option
+		feeds        []string
+		url          string
+		etag         string
+		lastModified string
+		headProbe    bool
+		duplicate    bool
+		header       string
+		wantErr      bool
+	}{
+		{name: "plain", feeds: []string{"https://example.com/feed.xml"}, url: "https://example.com/feed.xml"},
+		{name: "normalized", feeds: []string{" HTTPS://Example.COM:443/Feed.xml#top "}, url: "https://example.com/Feed.xml"},
+		{name: "header", opts: []Option{WithHeader("Authorization", "Bearer x")}, feeds: []string{"https://example.com/feed.xml"}, url: "https://example.com/feed.xml", header: "Bearer x"},
+		{name: "etag", opts: []Option{WithStore(store)}, feeds: []string{"https://example.com/etag.xml"}, url: "https://example.com/etag.xml", etag: `"v1"`},
+		{name: "last-modified", opts: []Option{WithStore(store)}, feeds: []string{"https://example.com/dated.xml"}, url: "https://example.com/dated.xml", lastModified: "Mon, 01 Jan 2024 10:00:00 GMT"},
+		{name: "head probe", opts: []Option{WithStore(store), WithHeadProbe()}, feeds: []string{"https://example.com/etag.xml"}, url: "https://example.com/etag.xml", etag: `"v1"`, headProbe: true},
+		// unchanged won't probe without something to probe with
+		{name: "head probe, no validators", opts: []Option{WithStore(store), WithHeadProbe()}, feeds: []string{"https://example.com/bare.xml"}, url: "https://example.com/bare.xml"},
+		{name: "head probe, not cached", opts: []Option{WithStore(store), WithHeadProbe()}, feeds: []string{"https://example.com/new.xml"}, url: "https://example.com/new.xml"},
+		{name: "duplicate", feeds: []string{"https://example.com/feed.xml", "https://EXAMPLE.com/feed.xml"}, url: "https://example.com/feed.xml", duplicate: true},
+		{name: "different path", feeds: []string{"https://example.com/feed.xml", "https://example.com/Feed.xml"}, url: "https://example.com/Feed.xml"},
+		{name: "malformed", feeds: []string{"https://exa mple.com/%zz"}, url: "https://exa mple.com/%zz", wantErr: true},
+	}
+	for _, tt := range tests {
+		plans := PlanFetch(tt.feeds, tt.opts...)
+		if len(plans) != len(tt.feeds) {
+			t.Fatalf("%s: got %d plans for %d feeds", tt.name, len(plans), len(tt.feeds))
+		}
+		// the feed under test is the last one
+		p := plans[len(plans)-1]
+		if (p.Err != nil) != tt.wantErr {
+			t.Errorf("%s: err %v, want error: %v", tt.name, p.Err, tt.wantErr)
+			continue
+		}
+		if p.URL != tt.url {
+			t.Errorf("%s: url %q, want %q", tt.name, p.URL, tt.url)
+		}
+		if p.ETag != tt.etag || p.LastModified != tt.lastModified {
+			t.Errorf("%s: validators %q, %q, want %q, %q", tt.name, p.ETag, p.LastModified, tt.etag, tt.lastModified)
+		}
+		if p.HeadProbe != tt.headProbe {
+			t.Errorf("%s: head probe %v, want %v", tt.name, p.HeadProbe, tt.headProbe)
+		}
+		if p.Duplicate != tt.duplicate {
+			t.Errorf("%s: duplicate %v, want %v", tt.name, p.Duplicate, tt.duplicate)
+		}
+		if tt.wantErr {
+			continue
+		}
+		if got := p.Header.Get("Authorization"); got != tt.header {
+			t.Errorf("%s: Authorization %q, want %q", tt.name, got, tt.header)
+		}
+		if tt.etag != "" && p.Header.Get("If-None-Match") != tt.etag {
+			t.Errorf("%s: If-None-Match %q, want %q", tt.name, p.Header.Get("If-None-Match"), tt.etag)
+		}
+	}
+}
+
+func TestFetchAllFeedsDedupsURLs(t *testing.T) {
+	srv := feedtest.NewServer([]byte(postsFeed("blog", 2)))
+	defer srv.Close()
+
+	f := NewFetcher()
+	feeds := []string{srv.URL, srv.URL + "#again", " " + srv.URL}
+	groups := f.FetchAllFeedsGrouped(context.Background(), feeds)
+	if got := srv.Requests(); got != 1 {
+		t.Errorf("feed fetched %d times, want 1", got)
+	}
+	for i, group := range groups {
+		if len(group) != 2 {
+			t.Errorf("feeds[%d] got %d posts, want 2", i, len(group))
+		}
+	}
+
+	// what PlanFetch said would happen
+	for i, p := range f.PlanFetch(feeds) {
+		if p.Duplicate != (i > 0) || p.URL != srv.URL {
+			t.Errorf("plan %d: %+v", i, p)
+		}
+	}
+
+	// its own options get a feed its own fetch
+	FetchAllFeedsWith(context.Background(), []FeedRequest{{URL: srv.URL}, {URL: srv.URL, Options: []Option{WithTimeout(time.Minute)}}})
+	if got := srv.Requests(); got != 3 {
+		t.Errorf("got %d requests in all, want 3", got)
+	}
+}
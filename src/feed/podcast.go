@@ -0,0 +1,89 @@
+package feed
+
+import "strings"
+
+// <itunes:owner><itunes:name/><itunes:email/></itunes:owner>
+type ItunesOwner struct {
+	Name  string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd name"`
+	Email string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd email"`
+}
+
+// PodcastInfo is the channel-level itunes metadata directories ask for.
+type PodcastInfo struct {
+	// Author is the show's credited author, which is often not the owner
+	Author string
+	// Owner is who the directories contact about the show
+	Owner    Author
+	Explicit bool
+}
+
+func getPodcastInfo(channel Channel) *PodcastInfo {
+	podcast := PodcastInfo{
+		Author: strings.TrimSpace(channel.ItunesAuthor),
+		Owner: Author{
+			Name:  strings.TrimSpace(channel.ItunesOwner.Name),
+			Email: strings.TrimSpace(channel.ItunesOwner.Email),
+		},
+	}
+
+	// apple has gone from yes/no/clean to true/false over the years
+	explicit := strings.ToLower(strings.TrimSpace(channel.ItunesExplicit))
+	podcast.Explicit = explicit == "yes" || explicit == "true" || explicit == "explicit"
+
+	if podcast == (PodcastInfo{}) && explicit == "" {
+		return nil
+	}
+	return &podcast
+}
+
+// <podcast:transcript url="" type="text/vtt" language="" rel=""/>
+type RawTranscript struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Language string `xml:"language,attr"`
+}
+
+// <podcast:chapters url="" type="application/json+chapters"/>
+type RawChapters struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// PodcastEpisode is the per-episode podcasting 2.0 metadata.
+type PodcastEpisode struct {
+	// Transcripts come in feed order, usually one per format (vtt, srt, html...)
+	Transcripts []Transcript
+	// ChaptersURL points at a JSON chapters file, not at the chapters themselves
+	ChaptersURL string
+}
+
+// Transcript is one transcript file. Type is its MIME type, like
+// "text/vtt" or "application/x-subrip", & Language is as the feed gave it.
+type Transcript struct {
+	URL      string
+	Type     string
+	Language string
+}
+
+func getPodcastEpisode(item Item, link, scheme string) *PodcastEpisode {
+	var episode PodcastEpisode
+	for _, raw := range item.Transcripts {
+		url := withScheme(raw.URL, scheme)
+		if url == "" {
+			continue
+		}
+		episode.Transcripts = append(episode.Transcripts, Transcript{
+			URL:      resolveURL(url, link),
+			Type:     strings.ToLower(strings.TrimSpace(raw.Type)),
+			Language: strings.TrimSpace(raw.Language),
+		})
+	}
+	if url := withScheme(item.Chapters.URL, scheme); url != "" {
+		episode.ChaptersURL = resolveURL(url, link)
+	}
+
+	if episode.Transcripts == nil && episode.ChaptersURL == "" {
+		return nil
+	}
+	return &episode
+}
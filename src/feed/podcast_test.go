@@ -0,0 +1,94 @@
+package feed
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const podcastNamespaces = `xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:podcast="https://podcastindex.org/namespace/1.0"`
+
+func parsePodcast(t *testing.T, channel string, opts ...Option) (FeedInfo, []BlogPost) {
+	t.Helper()
+	info, posts, err := ParseFeedInfo(strings.NewReader(`<rss version="2.0" `+podcastNamespaces+`><channel><title>The Show</title><link>https://show.example.com/</link>`+channel+`</channel></rss>`), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info, posts
+}
+
+func TestItunesDescription(t *testing.T) {
+	const enclosure = `<enclosure url="https://show.example.com/ep1.mp3" type="audio/mpeg" length="1000"/>`
+	tests := []struct {
+		name string
+		item string
+		want string
+	}{
+		{"summary only", `<itunes:summary>We talk about the history of &lt;b&gt;radio&lt;/b&gt;.</itunes:summary>`, "We talk about the history of radio."},
+		{"subtitle only", `<itunes:subtitle>A short one about radio.</itunes:subtitle>`, "A short one about radio."},
+		{"summary before subtitle", `<itunes:subtitle>A short one.</itunes:subtitle><itunes:summary>The long version.</itunes:summary>`, "The long version."},
+		{"empty description skipped", `<description></description><itunes:summary>The long version.</itunes:summary>`, "The long version."},
+		{"description wins", `<description>From the description.</description><itunes:summary>The long version.</itunes:summary>`, "From the description."},
+		{"nothing at all", ``, "Visit post for details."},
+	}
+	for _, tt := range tests {
+		_, posts := parsePodcast(t, `<item><title>Episode 1</title><link>https://show.example.com/1</link>`+enclosure+tt.item+`</item>`)
+		if posts[0].Summary != tt.want {
+			t.Errorf("%s: summary %q, want %q", tt.name, posts[0].Summary, tt.want)
+		}
+	}
+}
+
+func TestPodcastOwner(t *testing.T) {
+	const item = `<item><title>Episode 1</title><link>https://show.example.com/1</link></item>`
+	tests := []struct {
+		name    string
+		channel string
+		want    *PodcastInfo
+	}{
+		{"owner", `<itunes:author>Jane &amp; Bob</itunes:author><itunes:explicit>false</itunes:explicit>
+<itunes:owner><itunes:name> Show Productions </itunes:name><itunes:email>owner@show.example.com</itunes:email></itunes:owner>`,
+			&PodcastInfo{Author: "Jane & Bob", Owner: Author{Name: "Show Productions", Email: "owner@show.example.com"}}},
+		{"owner only", `<itunes:owner><itunes:name>Show Productions</itunes:name><itunes:email>owner@show.example.com</itunes:email></itunes:owner>`,
+			&PodcastInfo{Owner: Author{Name: "Show Productions", Email: "owner@show.example.com"}}},
+		{"explicit", `<itunes:explicit>yes</itunes:explicit>`, &PodcastInfo{Explicit: true}},
+		{"not a podcast", `<managingEditor>editor@example.com (Jane Doe)</managingEditor>`, nil},
+	}
+	for _, tt := range tests {
+		info, _ := parsePodcast(t, tt.channel+item)
+		if (info.Podcast == nil) != (tt.want == nil) || info.Podcast != nil && *info.Podcast != *tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, info.Podcast, tt.want)
+		}
+	}
+}
+
+func TestTranscriptsAndChapters(t *testing.T) {
+	_, posts := parsePodcast(t, `
+<item><title>Episode 2</title><link>https://show.example.com/episodes/2/</link>
+<enclosure url="https://cdn.example.com/ep2.mp3" length="123456" type="audio/mpeg"/>
+<podcast:transcript url="https://show.example.com/episodes/2/transcript.vtt" type="text/vtt" language="en"/>
+<podcast:transcript url="transcript.srt" type=" Application/X-Subrip "/>
+<podcast:transcript type="text/html"/>
+<podcast:chapters url="//cdn.example.com/ep2/chapters.json" type="application/json+chapters"/></item>
+<item><title>Episode 1</title><link>https://show.example.com/episodes/1/</link>
+<podcast:chapters url="chapters.json" type="application/json+chapters"/></item>
+<item><title>Trailer</title><link>https://show.example.com/trailer/</link>
+<enclosure url="https://cdn.example.com/trailer.mp3" length="1" type="audio/mpeg"/></item>`)
+
+	want := []*PodcastEpisode{
+		{
+			Transcripts: []Transcript{
+				{URL: "https://show.example.com/episodes/2/transcript.vtt", Type: "text/vtt", Language: "en"},
+				{URL: "https://show.example.com/episodes/2/transcript.srt", Type: "application/x-subrip"},
+			},
+			ChaptersURL: "https://cdn.example.com/ep2/chapters.json",
+		},
+		{ChaptersURL: "https://show.example.com/episodes/1/chapters.json"},
+		nil,
+	}
+	for i, post := range posts {
+		if !reflect.DeepEqual(post.Podcast, want[i]) {
+			t.Errorf("%s: got %+v, want %+v", post.Title, post.Podcast, want[i])
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package feed
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var relativeDateRegex = regexp.MustCompile(`^(\d+|an?|one)\s+(sec|second|min|minute|hr|hour|day|week|month|year)s?\s+ago$`)
+
+// nobody means "20000 days ago", & a big enough number overflows time.Duration
+// into the future
+const maxRelativeAmount = 10000
+
+// parseRelativeDate handles the "2 hours ago" / "yesterday" junk some scraped
+// feeds put in their date fields. it's a heuristic, so it only runs when
+// WithRelativeDates is set, and everything is anchored to ref.
+func parseRelativeDate(dateStr string, ref time.Time) (time.Time, bool) {
+	s := strings.ToLower(strings.TrimSpace(dateStr))
+
+	switch s {
+	case "just now", "now", "today":
+		return ref, true
+	case "yesterday":
+		return ref.AddDate(0, 0, -1), true
+	}
+
+	m := relativeDateRegex.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, false
+	}
+
+	n := 1
+	if m[1] != "a" && m[1] != "an" && m[1] != "one" {
+		var err error
+		if n, err = strconv.Atoi(m[1]); err != nil || n > maxRelativeAmount {
+			return time.Time{}, false
+		}
+	}
+
+	switch m[2] {
+	case "sec", "second":
+		return ref.Add(-time.Duration(n) * time.Second), true
+	case "min", "minute":
+		return ref.Add(-time.Duration(n) * time.Minute), true
+	case "hr", "hour":
+		return ref.Add(-time.Duration(n) * time.Hour), true
+	case "day":
+		return ref.AddDate(0, 0, -n), true
+	case "week":
+		return ref.AddDate(0, 0, -7*n), true
+	case "month":
+		return ref.AddDate(0, -n, 0), true
+	case "year":
+		return ref.AddDate(-n, 0, 0), true
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,74 @@
+package feed
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRelativeDate(t *testing.T) {
+	ref := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+		ok   bool
+	}{
+		{"5 minutes ago", ref.Add(-5 * time.Minute), true},
+		{"1 minute ago", ref.Add(-time.Minute), true},
+		{"a minute ago", ref.Add(-time.Minute), true},
+		{"30 sec ago", ref.Add(-30 * time.Second), true},
+		{"2 hours ago", ref.Add(-2 * time.Hour), true},
+		{"an hour ago", ref.Add(-time.Hour), true},
+		{"3 hrs ago", ref.Add(-3 * time.Hour), true},
+		{"3 days ago", time.Date(2024, 2, 27, 12, 0, 0, 0, time.UTC), true},
+		{"one day ago", time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC), true},
+		{"2 weeks ago", time.Date(2024, 2, 16, 12, 0, 0, 0, time.UTC), true},
+		{"1 month ago", time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC), true},
+		{"a year ago", time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC), true},
+		{"  Yesterday ", time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC), true},
+		{"yesterday", time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC), true},
+		{"just now", ref, true},
+		{"today", ref, true},
+		{"99999999999 days ago", time.Time{}, false},
+		{"in 2 days", time.Time{}, false},
+		{"2 fortnights ago", time.Time{}, false},
+		{"days ago", time.Time{}, false},
+		{"last tuesday", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRelativeDate(tt.in, ref)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseRelativeDate(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestWithRelativeDates(t *testing.T) {
+	ref := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	body := `<rss version="2.0"><channel><title>Scraped</title>
+<item><title>Old news item</title><link>https://example.com/1</link><pubDate>yesterday</pubDate></item>
+<item><title>New news item</title><link>https://example.com/2</link><pubDate>2 hours ago</pubDate></item>
+</channel></rss>`
+
+	tests := []struct {
+		name string
+		opts []Option
+		want []time.Time
+	}{
+		// without the option they're just unparseable, so the fetch time
+		{"off", nil, []time.Time{ref, ref}},
+		{"on", []Option{WithRelativeDates()}, []time.Time{ref.AddDate(0, 0, -1), ref.Add(-2 * time.Hour)}},
+	}
+	for _, tt := range tests {
+		opts := append([]Option{WithClock(func() time.Time { return ref })}, tt.opts...)
+		posts, err := ParseFeed(strings.NewReader(body), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, post := range posts {
+			if !post.PublishedAt.Equal(tt.want[i]) {
+				t.Errorf("%s: post %d at %v, want %v", tt.name, i, post.PublishedAt, tt.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// no matter how many retries, never wait longer than this between two tries
+const maxBackoff = 30 * time.Second
+
+// do sends req, trying again with exponential backoff on network errors &
+// the "come back later" statuses when WithRetries is on
+func (f *Fetcher) do(ctx context.Context, req *http.Request, o *options) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := f.client.Do(req)
+		if attempt >= o.retries || !retryable(ctx, resp, err) {
+			return resp, err
+		}
+
+		if resp != nil {
+			// drain so the connection can be reused for the next try
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		if err := sleepContext(ctx, backoff(o.retryBackoff, attempt)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	// a cloudflare challenge won't go away by asking again
+	if isCloudflareChallenge(resp) {
+		return false
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func backoff(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 0; i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// sleepContext is time.Sleep that gives up as soon as ctx does, so a
+// cancelled FetchAllFeedsContext doesn't sit out a long backoff
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// WithRetries retries a fetch up to n more times after a network error, a
+// 429 or a 502/503/504, waiting base, then 2*base, 4*base... in between
+// (capped at 30s). base <= 0 means 500ms.
+func WithRetries(n int, base time.Duration) Option {
+	return func(o *options) {
+		if base <= 0 {
+			base = 500 * time.Millisecond
+		}
+		o.retries = n
+		o.retryBackoff = base
+	}
+}
@@ -0,0 +1,87 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+func TestRetryCancelledMidBackoff(t *testing.T) {
+	srv := feedtest.NewServer(nil, feedtest.WithStatus(http.StatusServiceUnavailable))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// well after the first try, well before the first backoff is over
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	f := NewFetcher(WithRetries(3, 10*time.Second))
+	start := time.Now()
+	_, err := f.FetchFeedContext(ctx, srv.URL)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("took %v to notice the cancel", elapsed)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err %v, want context.Canceled", err)
+	}
+	if got := srv.Requests(); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var tries atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tries.Add(1) <= 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(postsFeed("flaky", 1)))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		retries int
+		wantErr bool
+		tries   int32
+	}{
+		{"no retries", 0, true, 1},
+		{"not enough", 1, true, 2},
+		{"enough", 2, false, 3},
+		{"plenty", 5, false, 3},
+	}
+	for _, tt := range tests {
+		tries.Store(0)
+		_, err := FetchFeed(srv.URL, WithRetries(tt.retries, time.Millisecond))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err %v, want error: %v", tt.name, err, tt.wantErr)
+		}
+		if got := tries.Load(); got != tt.tries {
+			t.Errorf("%s: %d tries, want %d", tt.name, got, tt.tries)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{5, maxBackoff},
+		{100, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(time.Second, tt.attempt); got != tt.want {
+			t.Errorf("backoff(1s, %d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
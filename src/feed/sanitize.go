@@ -0,0 +1,205 @@
+package feed
+
+import (
+	"encoding/xml"
+	"html"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// these never get to keep their contents, allowlisted or not
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true,
+	"embed": true, "noscript": true, "template": true,
+}
+
+// the only attributes that survive, per tag. everything else (on*, style,
+// class...) is gone.
+var allowedAttrs = map[string][]string{
+	"a":   {"href", "title"},
+	"img": {"src", "alt", "title", "width", "height"},
+}
+
+var voidTags = map[string]bool{"br": true, "hr": true, "img": true}
+
+// htmlSanitizer re-emits allowlisted tags from a token stream, same idea as
+// mdConverter: cut at limit visible characters & close whatever's open
+type htmlSanitizer struct {
+	b              strings.Builder
+	o              *options
+	base           string
+	allowed        map[string]bool
+	limit, visible int
+	done           bool
+	pendingSpace   bool
+	dropDepth      int
+	open           []string
+}
+
+// sanitizeHTML keeps only the tags in allowed, with only safe attributes.
+// the bool is false when the HTML can't be tokenized at all. a limit of 0
+// or less is no limit.
+func sanitizeHTML(input, base string, allowed map[string]bool, limit int, o *options) (string, bool) {
+	if limit <= 0 {
+		limit = math.MaxInt
+	}
+	s := &htmlSanitizer{o: o, base: base, allowed: allowed, limit: limit}
+	if o.strictTruncation {
+		s.limit -= utf8.RuneCountInString(o.ellipsis)
+	}
+
+	input = stripControl(dropControlRefs(input))
+	d := xml.NewDecoder(strings.NewReader(input))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	for !s.done {
+		tok, err := d.Token()
+		if err != nil {
+			if !endOfHTML(d, err, input) {
+				return "", false
+			}
+			break
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			s.start(tok)
+		case xml.EndElement:
+			s.end(strings.ToLower(tok.Name.Local))
+		case xml.CharData:
+			if s.dropDepth == 0 {
+				s.text(string(tok))
+			}
+		}
+	}
+
+	for i := len(s.open) - 1; i >= 0; i-- {
+		s.b.WriteString("</" + s.open[i] + ">")
+	}
+	return strings.TrimSpace(s.b.String()), true
+}
+
+func (s *htmlSanitizer) start(tok xml.StartElement) {
+	name := strings.ToLower(tok.Name.Local)
+	if droppedTags[name] {
+		s.dropDepth++
+		return
+	}
+	if s.dropDepth > 0 || !s.allowed[name] {
+		return
+	}
+
+	// <li>one<li>two, the second <li> closes the first
+	if (name == "li" || name == "p") && len(s.open) > 0 && s.open[len(s.open)-1] == name {
+		s.end(name)
+	}
+
+	s.flushSpace()
+	s.b.WriteString("<" + name)
+	for _, attr := range tok.Attr {
+		key := strings.ToLower(attr.Name.Local)
+		if !contains(allowedAttrs[name], key) {
+			continue
+		}
+
+		value := attr.Value
+		if key == "href" || key == "src" {
+			value = resolveURL(value, s.base)
+			if !isSafeURL(value) {
+				continue
+			}
+		}
+		s.b.WriteString(" " + key + `="` + html.EscapeString(value) + `"`)
+	}
+	s.b.WriteString(">")
+
+	if !voidTags[name] {
+		s.open = append(s.open, name)
+	}
+}
+
+func (s *htmlSanitizer) end(name string) {
+	if droppedTags[name] {
+		if s.dropDepth > 0 {
+			s.dropDepth--
+		}
+		return
+	}
+	if s.dropDepth > 0 || voidTags[name] {
+		return
+	}
+
+	// close down to the matching tag, so sloppy nesting still comes out balanced
+	for i := len(s.open) - 1; i >= 0; i-- {
+		if s.open[i] != name {
+			continue
+		}
+		for j := len(s.open) - 1; j >= i; j-- {
+			s.b.WriteString("</" + s.open[j] + ">")
+		}
+		s.open = s.open[:i]
+		return
+	}
+}
+
+func (s *htmlSanitizer) flushSpace() {
+	if s.pendingSpace && s.b.Len() > 0 {
+		s.b.WriteByte(' ')
+	}
+	s.pendingSpace = false
+}
+
+func (s *htmlSanitizer) text(text string) {
+	text = wsRegex.ReplaceAllString(text, " ")
+	if strings.HasPrefix(text, " ") {
+		s.pendingSpace = true
+	}
+	trailing := strings.HasSuffix(text, " ")
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+
+	runes := []rune(text)
+	remaining := s.limit - s.visible
+	if len(runes) <= remaining {
+		s.flushSpace()
+		s.b.WriteString(html.EscapeString(text))
+		s.visible += len(runes)
+		s.pendingSpace = trailing
+		return
+	}
+
+	cut := ""
+	if remaining > 0 {
+		cut = string(runes[:remaining])
+		if i := strings.LastIndexByte(cut, ' '); i > len(cut)/2 {
+			cut = cut[:i]
+		}
+	}
+	cut = strings.TrimRight(cut, " .,;:")
+	if cut != "" {
+		s.flushSpace()
+		s.b.WriteString(html.EscapeString(cut))
+	}
+	s.b.WriteString(html.EscapeString(s.o.ellipsis))
+	s.done = true
+}
+
+// javascript: & data: links are exactly what sanitizing is for
+func isSafeURL(u string) bool {
+	lower := strings.ToLower(strings.TrimSpace(u))
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "mailto:")
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
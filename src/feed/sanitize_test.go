@@ -0,0 +1,63 @@
+package feed
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAllowedTags(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		allowed     []string
+		want        string
+	}{
+		{"allowed kept", `<p>Some <em>emphasis</em> and <a href="https://example.com/x" title="X">a link</a></p>`, []string{"p", "em", "a"},
+			`<p>Some <em>emphasis</em> and <a href="https://example.com/x" title="X">a link</a></p>`},
+		{"others stripped, text kept", `<div class="x"><p>Some <span style="color:red">red</span> <strong>bold</strong> text</p></div>`, []string{"p"},
+			`<p>Some red bold text</p>`},
+		{"script & iframe gone with their contents", `<p>Before<script>alert(1)</script><iframe src="https://evil.example.com/">fallback</iframe> after</p>`, []string{"p", "script", "iframe"},
+			`<p>Before after</p>`},
+		{"unsafe attributes dropped", `<a href="javascript:alert(1)" onclick="steal()">click</a> <img src="/pic.png" onerror="x()" alt="pic">`, []string{"a", "img"},
+			`<a>click</a> <img src="https://example.com/pic.png" alt="pic">`},
+		{"unclosed tags closed", `<ul><li>one<li>two</ul><p><em>dangling`, []string{"ul", "li", "p", "em"},
+			`<ul><li>one</li><li>two</li></ul><p><em>dangling</em></p>`},
+		{"text escaped", `<p>1 &lt; 2 &amp; "quotes"</p>`, []string{"p"}, `<p>1 &lt; 2 &amp; &#34;quotes&#34;</p>`},
+	}
+	for _, tt := range tests {
+		item := `<item><title>Post</title><link>https://example.com/post</link><description><![CDATA[` + tt.description + `]]></description></item>`
+		post := parseItemXML(t, item, WithAllowedTags(tt.allowed...))
+		if post.SummaryHTML != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, post.SummaryHTML, tt.want)
+		}
+	}
+
+	if post := parseItemXML(t, `<item><title>Post</title><link>https://example.com/post</link><description>&lt;p&gt;Hi&lt;/p&gt;</description></item>`); post.SummaryHTML != "" {
+		t.Errorf("SummaryHTML %q without WithAllowedTags", post.SummaryHTML)
+	}
+}
+
+func TestAtomSummaryTypes(t *testing.T) {
+	tests := []struct {
+		name        string
+		summary     string
+		plain, html string
+	}{
+		{"text", `<summary type="text">Wrap it in &lt;b&gt; &amp; done</summary>`, "Wrap it in b & done", "Wrap it in &lt;b&gt; &amp; done"},
+		{"html", `<summary type="html">Wrap it in &lt;b&gt;bold&lt;/b&gt; &amp;amp; done</summary>`, "Wrap it in bold & done", "Wrap it in <b>bold</b> &amp; done"},
+		// plenty of feeds leave the type off & put HTML in anyway
+		{"no type", `<summary>Wrap it in &lt;b&gt;bold&lt;/b&gt;</summary>`, "Wrap it in bold", "Wrap it in <b>bold</b>"},
+		{"xhtml", `<summary type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>An <b>xhtml</b> summary</p></div></summary>`, "An xhtml summary", "<p>An <b>xhtml</b> summary</p>"},
+		{"xhtml, prefixed div", `<summary type="xhtml"><xhtml:div xmlns:xhtml="http://www.w3.org/1999/xhtml">Just text</xhtml:div></summary>`, "Just text", "Just text"},
+	}
+	for _, tt := range tests {
+		posts, err := ParseFeed(strings.NewReader(`<feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title>
+<entry><title>Post</title><id>urn:1</id><link href="https://example.com/1"/>`+tt.summary+`</entry></feed>`), WithAllowedTags("p", "b"))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if posts[0].Summary != tt.plain || posts[0].SummaryHTML != tt.html {
+			t.Errorf("%s: got %q & %q, want %q & %q", tt.name, posts[0].Summary, posts[0].SummaryHTML, tt.plain, tt.html)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package feed
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// SeenSet is how an Aggregator remembers posts. The Aggregator serializes
+// its calls, so implementations don't need their own locking.
+type SeenSet interface {
+	Has(key string) bool
+	Add(key string)
+}
+
+// the default: exact, and grows with every post ever seen
+type mapSet map[string]struct{}
+
+func (s mapSet) Has(key string) bool {
+	_, ok := s[key]
+	return ok
+}
+
+func (s mapSet) Add(key string) {
+	s[key] = struct{}{}
+}
+
+func (s mapSet) keys() []string {
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// BloomSet is a SeenSet with a fixed memory footprint, for aggregators that
+// track millions of posts. The catch is false positives: every so often a
+// genuinely new post looks seen and gets skipped. Nothing is ever reported
+// new twice though.
+type BloomSet struct {
+	bits   []uint64
+	hashes int
+}
+
+// NewBloomSet sizes a BloomSet for capacity keys at a false-positive rate of
+// fpRate (e.g. 0.001) once it's that full. Past capacity the rate climbs.
+// Roughly 1.8MB for a million keys at 0.001.
+func NewBloomSet(capacity int, fpRate float64) *BloomSet {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.001
+	}
+
+	// the textbook sizing: m = -n ln p / (ln 2)^2, k = m/n ln 2
+	m := math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := int(math.Round(m / float64(capacity) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &BloomSet{
+		bits:   make([]uint64, (int(m)+63)/64),
+		hashes: k,
+	}
+}
+
+// two independent hashes are enough to fake k of them (Kirsch-Mitzenmacher)
+func (b *BloomSet) locations(key string) (h1, h2 uint64) {
+	a := fnv.New64a()
+	a.Write([]byte(key))
+	c := fnv.New64()
+	c.Write([]byte(key))
+	return a.Sum64(), c.Sum64() | 1
+}
+
+func (b *BloomSet) Has(key string) bool {
+	h1, h2 := b.locations(key)
+	size := uint64(len(b.bits)) * 64
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % size
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *BloomSet) Add(key string) {
+	h1, h2 := b.locations(key)
+	size := uint64(len(b.bits)) * 64
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % size
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// MarshalBinary saves the filter, since unlike the default set it can't be
+// listed out through Aggregator.Export.
+func (b *BloomSet) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 8+8*len(b.bits))
+	binary.BigEndian.PutUint64(data, uint64(b.hashes))
+	for i, word := range b.bits {
+		binary.BigEndian.PutUint64(data[8+8*i:], word)
+	}
+	return data, nil
+}
+
+func (b *BloomSet) UnmarshalBinary(data []byte) error {
+	if len(data) < 16 || len(data)%8 != 0 {
+		return errors.New("bloom set: bad encoding")
+	}
+
+	// 0 hashes would make Has say yes to everything, & more hashes than
+	// there are bits is nothing NewBloomSet could have made
+	hashes := binary.BigEndian.Uint64(data)
+	words := len(data)/8 - 1
+	if hashes == 0 || hashes > uint64(words)*64 {
+		return fmt.Errorf("bloom set: bad encoding, %d hashes over %d bits", hashes, words*64)
+	}
+
+	b.hashes = int(hashes)
+	b.bits = make([]uint64, words)
+	for i := range b.bits {
+		b.bits[i] = binary.BigEndian.Uint64(data[8+8*i:])
+	}
+	return nil
+}
+
+// WithSeenSet swaps the Aggregator's exact seen-set for s, e.g. a BloomSet.
+// Only NewAggregator looks at it.
+func WithSeenSet(s SeenSet) Option {
+	return func(o *options) {
+		o.seenSet = s
+	}
+}
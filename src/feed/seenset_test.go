@@ -0,0 +1,102 @@
+package feed
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"runtime"
+	"testing"
+)
+
+// BenchmarkSeenSetMemory fills the default map set & a BloomSet with the
+// same GUIDs. bytes/key is the heap each one holds on to, fp-rate how often
+// the bloom filter calls a new key seen, which the map never does.
+func BenchmarkSeenSetMemory(b *testing.B) {
+	const keys = 200000
+	guid := func(i int) string { return fmt.Sprintf("https://example.com/posts/%d#guid", i) }
+
+	sets := []struct {
+		name string
+		make func() SeenSet
+	}{
+		{"map", func() SeenSet { return mapSet{} }},
+		{"bloom 0.001", func() SeenSet { return NewBloomSet(keys, 0.001) }},
+		{"bloom 0.01", func() SeenSet { return NewBloomSet(keys, 0.01) }},
+	}
+	for _, s := range sets {
+		b.Run(s.name, func(b *testing.B) {
+			var held uint64
+			var set SeenSet
+			for i := 0; i < b.N; i++ {
+				// last round's set mustn't count against this one
+				set = nil
+				before := heapInUse()
+				set = s.make()
+				for k := 0; k < keys; k++ {
+					set.Add(guid(k))
+				}
+				held += heapInUse() - before
+			}
+			b.ReportMetric(float64(held)/float64(b.N)/keys, "bytes/key")
+
+			b.StopTimer()
+			falsePositives := 0
+			for k := keys; k < 2*keys; k++ {
+				if set.Has(guid(k)) {
+					falsePositives++
+				}
+			}
+			b.ReportMetric(float64(falsePositives)/keys, "fp-rate")
+			runtime.KeepAlive(set)
+		})
+	}
+}
+
+// what's live on the heap after a full collection
+func heapInUse() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+func TestBloomSetUnmarshal(t *testing.T) {
+	set := NewBloomSet(100, 0.01)
+	set.Add("https://example.com/1")
+	data, err := set.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded BloomSet
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Has("https://example.com/1") || loaded.Has("https://example.com/2") {
+		t.Error("round trip lost track of what was seen")
+	}
+
+	withHashes := func(n uint64) []byte {
+		bad := append([]byte(nil), data...)
+		binary.BigEndian.PutUint64(bad, n)
+		return bad
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"no bits", data[:8]},
+		{"ragged", data[:len(data)-3]},
+		{"zero hashes", withHashes(0)},
+		// what a negative int comes out as
+		{"negative hashes", withHashes(math.MaxUint64)},
+		{"more hashes than bits", withHashes(uint64(len(data)-8)*8 + 1)},
+	}
+	for _, tt := range tests {
+		var b BloomSet
+		if err := b.UnmarshalBinary(tt.data); err == nil {
+			t.Errorf("%s: decoded without an error", tt.name)
+		}
+	}
+}
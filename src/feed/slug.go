@@ -0,0 +1,53 @@
+package feed
+
+import (
+	"strings"
+	"unicode"
+)
+
+// just the common latin letters, anything else non-ASCII gets dropped
+// (leaving a word break behind)
+var transliterations = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'č': "c", 'ć': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ı': "i",
+	'ł': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o",
+	'œ': "oe", 'ř': "r", 'ß': "ss", 'š': "s", 'ś': "s", 'ş': "s", 'ť': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y", 'ž': "z", 'ź': "z", 'ż': "z",
+}
+
+// slugify turns a title into something URL & filename safe, like
+// "Ünïcode Is Fun!" -> "unicode-is-fun". same title in, same slug out.
+func slugify(title string) string {
+	var b strings.Builder
+	pendingHyphen := false
+
+	for _, r := range strings.ToLower(title) {
+		var chunk string
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			chunk = string(r)
+		case transliterations[r] != "":
+			chunk = transliterations[r]
+		case r == '\'' || r == '’':
+			// "don't" should be "dont", not "don-t"
+			continue
+		default:
+			// punctuation, spaces of any kind & scripts we can't transliterate
+			// all still separate words: "Go言語 rocks" is "go-rocks"
+			pendingHyphen = true
+			continue
+		}
+
+		if pendingHyphen && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		pendingHyphen = false
+		b.WriteString(chunk)
+	}
+
+	return b.String()
+}
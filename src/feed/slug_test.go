@@ -0,0 +1,62 @@
+package feed
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Hello World", "hello-world"},
+		{"Ünïcode Is Fun!", "unicode-is-fun"},
+		{"Crème Brûlée & Smørrebrød", "creme-brulee-smorrebrod"},
+		{"Straße in Łódź", "strasse-in-lodz"},
+		{"Don't Stop Believin’", "dont-stop-believin"},
+		{"  Go 1.22: What's New?  ", "go-1-22-whats-new"},
+		{"C++ / Rust -- a comparison", "c-rust-a-comparison"},
+		{"Café — Bar", "cafe-bar"},
+		{"Hello—World and Go言語 rocks", "hello-world-and-go-rocks"},
+		{"Non\u00a0breaking\u3000spaces", "non-breaking-spaces"},
+		{"«Quoted»words…here", "quoted-words-here"},
+		{"東京Tokyo大阪Osaka", "tokyo-osaka"},
+		{"日本語のタイトル", ""},
+		{"日本語 and English", "and-english"},
+		{"!!!", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got := slugify(tt.title)
+		if got != tt.want {
+			t.Errorf("slugify(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+		if got != slugify(tt.title) {
+			t.Errorf("slugify(%q) isn't deterministic", tt.title)
+		}
+	}
+}
+
+func TestWithSlugs(t *testing.T) {
+	body := `<rss version="2.0"><channel><title>Blog</title>
+<item><title>Ünïcode Is Fun!</title><link>https://example.com/1</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate></item>
+</channel></rss>`
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"off by default", nil, ""},
+		{"on", []Option{WithSlugs()}, "unicode-is-fun"},
+	}
+	for _, tt := range tests {
+		posts, err := ParseFeed(strings.NewReader(body), tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if posts[0].Slug != tt.want {
+			t.Errorf("%s: slug %q, want %q", tt.name, posts[0].Slug, tt.want)
+		}
+	}
+}
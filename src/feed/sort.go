@@ -0,0 +1,51 @@
+package feed
+
+import (
+	"sort"
+	"time"
+)
+
+// NewestFirst is the default ordering: most recently published first.
+func NewestFirst(a, b BlogPost) bool {
+	return a.PublishedAt.After(b.PublishedAt)
+}
+
+// RecentlyUpdatedFirst orders by UpdatedAt instead, for SortByUpdated.
+func RecentlyUpdatedFirst(a, b BlogPost) bool {
+	return a.UpdatedAt.After(b.UpdatedAt)
+}
+
+// SortPostsFunc sorts posts in place by any ordering you like. It's stable,
+// so posts less considers equal keep their relative order.
+func SortPostsFunc(posts []BlogPost, less func(a, b BlogPost) bool) {
+	sort.SliceStable(posts, func(i, j int) bool {
+		return less(posts[i], posts[j])
+	})
+}
+
+// MostRecentlyActiveFirst orders by the later of PublishedAt & UpdatedAt,
+// for SortByActivity.
+func MostRecentlyActiveFirst(a, b BlogPost) bool {
+	return lastActive(a).After(lastActive(b))
+}
+
+func lastActive(post BlogPost) time.Time {
+	if post.UpdatedAt.After(post.PublishedAt) {
+		return post.UpdatedAt
+	}
+	return post.PublishedAt
+}
+
+func (order SortOrder) less() func(a, b BlogPost) bool {
+	switch order {
+	case SortByUpdated:
+		return RecentlyUpdatedFirst
+	case SortByActivity:
+		return MostRecentlyActiveFirst
+	}
+	return NewestFirst
+}
+
+func sortPosts(posts []BlogPost, order SortOrder) {
+	SortPostsFunc(posts, order.less())
+}
@@ -0,0 +1,203 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+func TestSortPostsFunc(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	posts := []BlogPost{
+		{Title: "a", Author: "zed", PublishedAt: day(2), UpdatedAt: day(2)},
+		{Title: "b", Author: "amy", PublishedAt: day(1), UpdatedAt: day(9)},
+		{Title: "c", Author: "amy", PublishedAt: day(3), UpdatedAt: day(3)},
+		{Title: "d", Author: "bo", PublishedAt: day(2), UpdatedAt: day(4)},
+	}
+
+	tests := []struct {
+		name string
+		less func(a, b BlogPost) bool
+		want []string
+	}{
+		// a & d tie, & it's stable
+		{"newest first", NewestFirst, []string{"c", "a", "d", "b"}},
+		{"recently updated first", RecentlyUpdatedFirst, []string{"b", "d", "c", "a"}},
+		{"most recently active first", MostRecentlyActiveFirst, []string{"b", "d", "c", "a"}},
+		{"by author", func(a, b BlogPost) bool { return a.Author < b.Author }, []string{"b", "c", "d", "a"}},
+		{"oldest first", func(a, b BlogPost) bool { return NewestFirst(b, a) }, []string{"b", "a", "d", "c"}},
+	}
+	for _, tt := range tests {
+		sorted := append([]BlogPost(nil), posts...)
+		SortPostsFunc(sorted, tt.less)
+		var titles []string
+		for _, post := range sorted {
+			titles = append(titles, post.Title)
+		}
+		if !reflect.DeepEqual(titles, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, titles, tt.want)
+		}
+	}
+}
+
+func TestSortOrder(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	posts := []BlogPost{
+		{Title: "old but edited", PublishedAt: day(1), UpdatedAt: day(9)},
+		{Title: "new", PublishedAt: day(5), UpdatedAt: day(5)},
+	}
+	tests := []struct {
+		order SortOrder
+		first string
+	}{
+		{SortByPublished, "new"},
+		{SortByUpdated, "old but edited"},
+		{SortByActivity, "old but edited"},
+	}
+	for _, tt := range tests {
+		sorted := append([]BlogPost(nil), posts...)
+		sortPosts(sorted, tt.order)
+		if sorted[0].Title != tt.first {
+			t.Errorf("order %d: %q first, want %q", tt.order, sorted[0].Title, tt.first)
+		}
+	}
+}
+
+// curatedFeed is a "top stories" list, deliberately not in date order
+func curatedFeed(name string, days ...int) string {
+	body := `<rss version="2.0"><channel><title>` + name + `</title>`
+	for _, d := range days {
+		body += fmt.Sprintf(`<item><title>%s %d</title><link>https://%s.example.com/%d</link><pubDate>%s</pubDate></item>`,
+			name, d, name, d, time.Date(2024, 1, d, 10, 0, 0, 0, time.UTC).Format(time.RFC1123))
+	}
+	return body + `</channel></rss>`
+}
+
+func TestPreserveOrder(t *testing.T) {
+	a := feedtest.NewServer([]byte(curatedFeed("a", 3, 9, 1)))
+	defer a.Close()
+	b := feedtest.NewServer([]byte(curatedFeed("b", 2, 8)))
+	defer b.Close()
+	urls := []string{a.URL, b.URL}
+
+	titles := func(posts []BlogPost) []string {
+		var got []string
+		for _, post := range posts {
+			got = append(got, post.Title)
+		}
+		return got
+	}
+
+	single, err := FetchFeed(a.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := titles(single), []string{"a 3", "a 9", "a 1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FetchFeed: got %q, want document order %q", got, want)
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"sorted", nil, []string{"a 9", "b 8", "a 3", "b 2", "a 1"}},
+		{"preserved", []Option{WithPreserveOrder()}, []string{"a 3", "a 9", "a 1", "b 2", "b 8"}},
+		{"preserved & interleave ignored", []Option{WithPreserveOrder(), WithMergeStrategy(MergeInterleave)}, []string{"a 3", "a 9", "a 1", "b 2", "b 8"}},
+		{"preserved & capped", []Option{WithPreserveOrder(), WithMaxPerSource(2)}, []string{"a 3", "a 9", "b 2", "b 8"}},
+	}
+	for _, tt := range tests {
+		if got := titles(FetchAllFeeds(urls, tt.opts...)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	grouped := NewFetcher(WithPreserveOrder()).FetchAllFeedsGrouped(context.Background(), urls)
+	if got, want := titles(grouped[0]), []string{"a 3", "a 9", "a 1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("grouped: got %q, want %q", got, want)
+	}
+	grouped = NewFetcher().FetchAllFeedsGrouped(context.Background(), urls)
+	if got, want := titles(grouped[0]), []string{"a 9", "a 3", "a 1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("grouped & sorted: got %q, want %q", got, want)
+	}
+}
+
+func TestSortByActivity(t *testing.T) {
+	srv := feedtest.NewServer([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><title>edited</title>
+<entry><title>old but edited</title><id>urn:1</id><link href="https://edited.example.com/1"/><published>2024-01-01T10:00:00Z</published><updated>2024-01-20T10:00:00Z</updated></entry>
+<entry><title>newest</title><id>urn:2</id><link href="https://edited.example.com/2"/><published>2024-01-15T10:00:00Z</published></entry>
+<entry><title>middle</title><id>urn:3</id><link href="https://edited.example.com/3"/><published>2024-01-10T10:00:00Z</published><updated>2024-01-11T10:00:00Z</updated></entry>
+</feed>`))
+	defer srv.Close()
+
+	tests := []struct {
+		order SortOrder
+		want  []string
+	}{
+		{SortByPublished, []string{"newest", "middle", "old but edited"}},
+		{SortByActivity, []string{"old but edited", "newest", "middle"}},
+	}
+	for _, tt := range tests {
+		posts := FetchAllFeeds([]string{srv.URL}, WithSortOrder(tt.order))
+		var got []string
+		for _, post := range posts {
+			got = append(got, post.Title)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("order %d: got %q, want %q", tt.order, got, tt.want)
+		}
+
+		// sorting by activity mustn't pass an edit off as the publish date
+		top := posts[0]
+		if tt.order == SortByActivity {
+			published := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+			if !top.PublishedAt.Equal(published) || !top.Date.Equal(published) {
+				t.Errorf("PublishedAt %v, Date %v, want both %v", top.PublishedAt, top.Date, published)
+			}
+			if updated := time.Date(2024, 1, 20, 10, 0, 0, 0, time.UTC); !top.UpdatedAt.Equal(updated) {
+				t.Errorf("UpdatedAt %v, want %v", top.UpdatedAt, updated)
+			}
+		}
+	}
+}
+
+func TestMaxTotalPosts(t *testing.T) {
+	a := feedtest.NewServer([]byte(curatedFeed("a", 2, 6, 10)))
+	defer a.Close()
+	// the same posts again from somewhere else, which dedup has to fold in
+	// before anything gets counted
+	mirror := feedtest.NewServer([]byte(curatedFeed("a", 2, 6, 10)))
+	defer mirror.Close()
+	b := feedtest.NewServer([]byte(curatedFeed("b", 3, 7, 9)))
+	defer b.Close()
+	feeds := []string{a.URL, mirror.URL, b.URL}
+
+	tests := []struct {
+		name string
+		max  int
+		want []string
+	}{
+		{"no cap", 0, []string{"a 10", "b 9", "b 7", "a 6", "b 3", "a 2"}},
+		// newest 4 across every feed, not 4 from each or the first 4 fetched
+		{"cap 4", 4, []string{"a 10", "b 9", "b 7", "a 6"}},
+		{"cap 1", 1, []string{"a 10"}},
+		{"cap above the total", 50, []string{"a 10", "b 9", "b 7", "a 6", "b 3", "a 2"}},
+	}
+	for _, tt := range tests {
+		posts := FetchAllFeeds(feeds, WithMaxTotalPosts(tt.max))
+		var got []string
+		for _, post := range posts {
+			got = append(got, post.Title)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+		if tt.max > 0 && cap(posts) > tt.max {
+			t.Errorf("%s: still holding on to room for %d posts", tt.name, cap(posts))
+		}
+	}
+}
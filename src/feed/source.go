@@ -0,0 +1,167 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// RawSource is RSS's <source url="feed">Feed Title</source>, or atom's
+// <source> with its own <title> & <link>s
+type RawSource struct {
+	URL   string `xml:"url,attr"`
+	Text  string `xml:",chardata"`
+	Title string `xml:"title"`
+	Links []Link `xml:"link"`
+}
+
+// Origin is where a syndicated post was first published, when the feed says.
+type Origin struct {
+	Title string
+	// URL is the original feed, not the original post
+	URL string
+}
+
+func getOrigin(item Item) Origin {
+	origin := Origin{
+		Title: strings.TrimSpace(item.Source.Text),
+		URL:   strings.TrimSpace(item.Source.URL),
+	}
+	if origin.Title == "" {
+		origin.Title = strings.TrimSpace(item.Source.Title)
+	}
+
+	if origin.URL == "" {
+		for _, link := range item.Source.Links {
+			if strings.EqualFold(link.Rel, "self") {
+				origin.URL = link.URL()
+				break
+			}
+		}
+	}
+
+	return origin
+}
+
+const (
+	// how many source feeds one Fetcher will look up at once
+	sourceLookupConcurrency = 4
+	// & how many different ones a single feed gets to trigger
+	maxSourceLookups = 20
+)
+
+// sourceTitles remembers every source feed title we've looked up, misses
+// included, so each source is only ever fetched once per Fetcher
+type sourceTitles struct {
+	mu      sync.Mutex
+	lookups map[string]*sourceLookup
+	sem     chan struct{}
+}
+
+// done is only set once there's a real answer, title or definite miss. a
+// lookup cut short by its context gets another go next time.
+type sourceLookup struct {
+	mu    sync.Mutex
+	done  bool
+	title string
+}
+
+func newSourceTitles() *sourceTitles {
+	return &sourceTitles{
+		lookups: make(map[string]*sourceLookup),
+		sem:     make(chan struct{}, sourceLookupConcurrency),
+	}
+}
+
+func (s *sourceTitles) lookup(url string) *sourceLookup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.lookups[url]
+	if !ok {
+		l = &sourceLookup{}
+		s.lookups[url] = l
+	}
+	return l
+}
+
+// fillOriginTitles fetches the title of every source feed that only came
+// with a URL. feedURL is the feed the posts came from.
+func (f *Fetcher) fillOriginTitles(ctx context.Context, feedURL string, posts []BlogPost, o *options) {
+	urls := make(map[string]bool)
+	for _, post := range posts {
+		if post.Origin.Title == "" && post.Origin.URL != "" && len(urls) < maxSourceLookups {
+			urls[post.Origin.URL] = true
+		}
+	}
+	if len(urls) == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for url := range urls {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			f.sourceTitle(ctx, url, o.forTarget(feedURL, url))
+		}(url)
+	}
+	wg.Wait()
+
+	for i := range posts {
+		if posts[i].Origin.Title == "" && urls[posts[i].Origin.URL] {
+			url := posts[i].Origin.URL
+			posts[i].Origin.Title = f.sourceTitle(ctx, url, o.forTarget(feedURL, url))
+		}
+	}
+}
+
+func (f *Fetcher) sourceTitle(ctx context.Context, url string, o *options) string {
+	l := f.sources.lookup(url)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done {
+		return l.title
+	}
+
+	select {
+	case f.sources.sem <- struct{}{}:
+		defer func() { <-f.sources.sem }()
+	case <-ctx.Done():
+		return ""
+	}
+
+	// the source's own posts don't matter, & it doesn't get to
+	// trigger lookups of its own
+	lo := *o
+	lo.sourceTitles = false
+	lo.metadataOnly = true
+
+	body, err := f.fetchBody(ctx, url, &lo)
+	if err != nil {
+		// a fetch we gave up on says nothing about the source
+		l.done = ctx.Err() == nil
+		return ""
+	}
+	info, err := parseFeed(bytes.NewReader(body), &lo, func(BlogPost) error { return nil })
+	// an empty feed still has a title
+	if err != nil && !errors.Is(err, ErrNoItemsFound) {
+		l.done = true
+		return ""
+	}
+
+	l.done = true
+	l.title = strings.TrimSpace(info.Title)
+	return l.title
+}
+
+// WithSourceTitleLookup fetches the title of the original feed for reposted
+// items whose <source> only gives a URL. Each source is fetched at most once
+// per Fetcher, a few at a time, and at most 20 per feed.
+func WithSourceTitleLookup() Option {
+	return func(o *options) {
+		o.sourceTitles = true
+	}
+}
@@ -0,0 +1,113 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+const sourceFeed = `<rss version="2.0"><channel><title>  The Original  </title></channel></rss>`
+
+// headerServer serves body & remembers the Authorization of every request
+type headerServer struct {
+	*httptest.Server
+	mu   sync.Mutex
+	auth []string
+}
+
+func newHeaderServer(body string) *headerServer {
+	hs := &headerServer{}
+	hs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hs.mu.Lock()
+		hs.auth = append(hs.auth, r.Header.Get("Authorization"))
+		hs.mu.Unlock()
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, body)
+	}))
+	return hs
+}
+
+func (hs *headerServer) seen() []string {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	return append([]string(nil), hs.auth...)
+}
+
+func TestSourceTitleLookup(t *testing.T) {
+	origin := newHeaderServer(sourceFeed)
+	defer origin.Close()
+
+	syndicated := fmt.Sprintf(`<rss version="2.0"><channel><title>Planet</title>
+<item><title>Reposted</title><link>https://example.com/1</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate><source url="%s"></source></item>
+</channel></rss>`, origin.URL)
+	planet := newHeaderServer(syndicated)
+	defer planet.Close()
+
+	f := NewFetcher(WithSourceTitleLookup(), WithHeader("Authorization", "Bearer secret"))
+	posts, err := f.FetchFeed(planet.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].Origin.Title != "The Original" {
+		t.Fatalf("got %+v, want the source's title", posts)
+	}
+
+	if got := planet.seen(); len(got) != 1 || got[0] != "Bearer secret" {
+		t.Errorf("feed got Authorization %q, want the caller's", got)
+	}
+	if got := origin.seen(); len(got) != 1 || got[0] != "" {
+		t.Errorf("source feed on another host got Authorization %q", got)
+	}
+}
+
+func TestSourceTitleRetriesCancelled(t *testing.T) {
+	srv := feedtest.NewServer([]byte(sourceFeed))
+	defer srv.Close()
+
+	f := NewFetcher(WithSourceTitleLookup())
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"cancelled", cancelled, ""},
+		{"after cancel", context.Background(), "The Original"},
+		{"cached", cancelled, "The Original"},
+	}
+	for _, tt := range tests {
+		if got := f.sourceTitle(tt.ctx, srv.URL, f.opts); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := srv.Requests(); got != 1 {
+		t.Errorf("source fetched %d times, want 1", got)
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	tests := []struct {
+		feed, target string
+		want         bool
+	}{
+		{"https://example.com/feed.xml", "https://example.com/other.xml", true},
+		{"https://example.com/feed.xml", "https://EXAMPLE.com/", true},
+		{"https://example.com/feed.xml", "http://example.com/", false},
+		{"https://example.com/feed.xml", "https://example.com:8443/", false},
+		{"https://example.com/feed.xml", "https://cdn.example.com/", false},
+		{"https://example.com/feed.xml", "/relative", false},
+		{"not a url", "not a url", false},
+	}
+	for _, tt := range tests {
+		if got := sameOrigin(tt.feed, tt.target); got != tt.want {
+			t.Errorf("sameOrigin(%q, %q) = %v, want %v", tt.feed, tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package feed
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// feedStream walks a document with a decoder instead of unmarshalling the
+// whole thing, so each item gets decoded, turned into a post & handed on
+// before the next one is even read. the feed's own elements are collected
+// into a Feed as they go by. posts are built against whatever of that came
+// before the first item, which in any real feed is all of it.
+type feedStream struct {
+	d    *xml.Decoder
+	o    *options
+	feed Feed
+	emit func(BlogPost) error
+	// what emit failed with, as opposed to the document being broken
+	emitErr error
+
+	// worked out at the first item
+	started bool
+	info    FeedInfo
+	scheme  string
+	itemOpt *options
+	perPost bool
+	// the whole feed is the wrong language, its items just get skipped
+	skip bool
+
+	items int
+	dates []time.Time
+}
+
+func isItem(start xml.StartElement) bool {
+	return start.Name.Local == "item" || start.Name.Local == "entry"
+}
+
+func (s *feedStream) run() error {
+	root, err := s.root()
+	if err != nil {
+		return err
+	}
+	if err := s.decodeInto(&s.feed, root, nil); err != nil {
+		return err
+	}
+
+	return s.children(func(start xml.StartElement) error {
+		switch {
+		case isItem(start):
+			return s.item(start, -1)
+		case start.Name.Local == "channel":
+			return s.channel(start)
+		}
+		return s.decodeInto(&s.feed, xml.StartElement{Name: root.Name}, &start)
+	})
+}
+
+// root skips the prolog (declaration, comments, doctype) to the first element
+func (s *feedStream) root() (xml.StartElement, error) {
+	for {
+		tok, err := s.d.Token()
+		if errors.Is(err, io.EOF) {
+			// otherwise this would just say "EOF", which tells nobody anything
+			return xml.StartElement{}, ErrEmptyFeed
+		}
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			return tok, nil
+		case xml.CharData:
+			if len(bytes.TrimSpace(tok)) > 0 {
+				return xml.StartElement{}, errors.New("text before the root element")
+			}
+		}
+	}
+}
+
+// children calls fn with each child element of the one just opened, which
+// fn has to consume entirely, until that one closes
+func (s *feedStream) children(fn func(xml.StartElement) error) error {
+	for {
+		tok, err := s.d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if err := fn(tok); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+func (s *feedStream) channel(start xml.StartElement) error {
+	s.feed.Channels = append(s.feed.Channels, Channel{})
+	i := len(s.feed.Channels) - 1
+
+	return s.children(func(child xml.StartElement) error {
+		if isItem(child) {
+			return s.item(child, i)
+		}
+		return s.decodeInto(&s.feed.Channels[i], xml.StartElement{Name: start.Name}, &child)
+	})
+}
+
+// begin works out the feed-level bits every post needs, from the header so far
+func (s *feedStream) begin() {
+	s.started = true
+	if len(s.feed.Channels) > 0 {
+		s.feed.Channel = s.feed.Channels[0]
+	}
+
+	s.info = getFeedInfo(s.feed, s.o)
+	s.scheme = feedScheme(s.o.feedURL, s.info.SelfURL, s.feed.Base, s.info.Link)
+	s.itemOpt = s.o
+	if s.o.generatorQuirks {
+		s.itemOpt = withQuirks(s.o, s.info.Generator)
+	}
+
+	// unless it doesn't say & we're guessing post by post
+	s.perPost = s.o.languageDetect && s.info.Language == ""
+	s.skip = !s.perPost && !s.o.languageAllowed(s.info.Language)
+}
+
+// item decodes one item & sends it through the filters. channel is its
+// index in feed.Channels, -1 for the items rdf & atom keep at the top level.
+func (s *feedStream) item(start xml.StartElement, channel int) error {
+	if !s.started {
+		s.begin()
+	}
+	if s.skip {
+		s.items++
+		return s.d.Skip()
+	}
+
+	o := s.itemOpt
+	// an item cut off halfway is no use to anyone, so it doesn't count
+	item, err := decodeItem(s.d, &start, o.keepRawXML)
+	if err != nil {
+		return err
+	}
+	s.items++
+	if s.info.Format == "atom" && s.info.Version == "0.3" {
+		item = atom03Dates(item)
+	}
+
+	// the odd aggregated document has several <channel>s, & every post
+	// belongs to its own
+	source := s.info.Title
+	if channel >= 0 {
+		if title := strings.TrimSpace(s.feed.Channels[channel].Title); title != "" {
+			source = title
+		}
+	}
+
+	post, dated := itemPost(item, source, s.info.Language, s.scheme, o)
+	if dated {
+		s.dates = append(s.dates, post.PublishedAt)
+	}
+	if o.untitledPolicy == DropUntitled && untitled(post) {
+		return nil
+	}
+
+	if s.perPost {
+		description, _ := getDescription(item, o)
+		post.Language = detectLanguage(post.Title + " " + description)
+		if !o.languageAllowed(post.Language) {
+			return nil
+		}
+	}
+	if !o.authorAllowed(post.Author) {
+		return nil
+	}
+	if !o.applyFuturePolicy(&post) {
+		return nil
+	}
+	if err := s.emit(post); err != nil {
+		s.emitErr = err
+		return err
+	}
+	return nil
+}
+
+// decodeInto decodes child as though it were the only thing inside parent,
+// then adds whatever it set to dst (a *Feed or *Channel). with no child
+// it's just parent's attributes.
+func (s *feedStream) decodeInto(dst any, parent xml.StartElement, child *xml.StartElement) error {
+	part := reflect.New(reflect.TypeOf(dst).Elem())
+	sub := xml.NewTokenDecoder(&subtree{d: s.d, parent: parent, child: child})
+	if err := sub.Decode(part.Interface()); err != nil {
+		return err
+	}
+
+	mergeFields(reflect.ValueOf(dst).Elem(), part.Elem())
+	return nil
+}
+
+// mergeFields copies every field src set into dst, appending to slices so
+// repeated elements (links, categories...) add up like they do in one
+// big Unmarshal
+func mergeFields(dst, src reflect.Value) {
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Field(i)
+		if field.IsZero() {
+			continue
+		}
+		if field.Kind() == reflect.Slice {
+			dst.Field(i).Set(reflect.AppendSlice(dst.Field(i), field))
+			continue
+		}
+		dst.Field(i).Set(field)
+	}
+}
+
+// subtree is an xml.TokenReader for decodeInto: parent's start, child's
+// tokens straight off the real decoder, then parent's end
+type subtree struct {
+	d      *xml.Decoder
+	parent xml.StartElement
+	child  *xml.StartElement
+	state  int
+	depth  int
+}
+
+func (st *subtree) Token() (xml.Token, error) {
+	switch st.state {
+	case 0:
+		st.state = 1
+		return st.parent.Copy(), nil
+	case 1:
+		if st.child == nil {
+			st.state = 3
+			return st.parent.End(), nil
+		}
+		st.state, st.depth = 2, 1
+		return st.child.Copy(), nil
+	case 2:
+		tok, err := st.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			st.depth++
+		case xml.EndElement:
+			st.depth--
+			if st.depth == 0 {
+				st.state = 4
+			}
+		}
+		return xml.CopyToken(tok), nil
+	case 4:
+		st.state = 3
+		return st.parent.End(), nil
+	}
+	return nil, io.EOF
+}
+
+// decodeItem decodes one <item> or <entry>. only WithRawItemXML pays for
+// a second copy of its markup.
+func decodeItem(d *xml.Decoder, start *xml.StartElement, keepRaw bool) (Item, error) {
+	if !keepRaw {
+		var item Item
+		err := d.DecodeElement(&item, start)
+		return item, err
+	}
+
+	var raw struct {
+		Item
+		Inner string `xml:",innerxml"`
+	}
+	err := d.DecodeElement(&raw, start)
+	raw.Item.Raw = raw.Inner
+	return raw.Item, err
+}
+
+// controlReader is dropIllegalControls for a stream. they're all single
+// bytes in UTF-8, so dropping bytes can't split a character.
+type controlReader struct {
+	r io.Reader
+}
+
+func (cr controlReader) Read(p []byte) (int, error) {
+	for {
+		n, err := cr.r.Read(p)
+		kept := 0
+		for _, b := range p[:n] {
+			if !illegalControl(rune(b)) {
+				p[kept] = b
+				kept++
+			}
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return kept, readFailure{err}
+		}
+		// a read that was nothing but control characters isn't the end
+		if kept > 0 || err != nil || n == 0 {
+			return kept, err
+		}
+	}
+}
+
+// readFailure marks an error as the reader's, not the document's
+type readFailure struct {
+	err error
+}
+
+func (rf readFailure) Error() string { return rf.err.Error() }
+func (rf readFailure) Unwrap() error { return rf.err }
+
+// feedReader gets r ready for a feedStream. WithLenient's unwrapping &
+// WithNamespaceReport need the whole document, so only then does it get
+// read up front, & body is nil otherwise.
+func feedReader(r io.Reader, o *options) (stream io.Reader, body []byte, err error) {
+	if !o.lenient && !o.namespaceReport {
+		br := bufio.NewReader(r)
+		// windows tools like to start files with a UTF-8 BOM
+		if bom, _ := br.Peek(len(utf8BOM)); bytes.Equal(bom, utf8BOM) {
+			br.Discard(len(utf8BOM))
+		}
+		return controlReader{r: br}, nil, nil
+	}
+
+	body, err = io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	body = dropIllegalControls(bytes.TrimPrefix(body, utf8BOM))
+	if o.lenient {
+		body = unwrapFeed(body)
+	}
+	return bytes.NewReader(body), body, nil
+}
@@ -0,0 +1 @@
+feedCallback({"status": "ok", "xml": "<rss version=\"2.0\"><channel><title>Wrapped</title><item><title>Post</title><link>https://wrapped.example.com/1</link><description>Tom &amp; Jerry</description></item></channel></rss>"});
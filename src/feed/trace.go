@@ -0,0 +1,114 @@
+package feed
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings is where one feed fetch spent its time, for telling a slow DNS
+// server from a slow handshake from a slow feed. Phases that didn't happen
+// (no DNS for an IP, no handshake on a reused connection) stay 0.
+type Timings struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// Wait is from the request going out to the first byte coming back,
+	// i.e. the server thinking
+	Wait time.Duration
+	// TimeToFirstByte is from the start of the fetch to the first byte
+	TimeToFirstByte time.Duration
+	// Total is the whole fetch, body included
+	Total time.Duration
+	// ReusedConn means an idle kept-alive connection was used
+	ReusedConn bool
+}
+
+// WithRequestTiming traces every feed GET with httptrace and calls fn with
+// the breakdown once the body is read (or the fetch failed). With retries
+// it's the last attempt that gets timed. Calls can come from several
+// goroutines at once. Off by default, since tracing isn't free.
+func WithRequestTiming(fn func(url string, t Timings)) Option {
+	return func(o *options) {
+		o.onTiming = fn
+	}
+}
+
+// requestTimer collects one fetch's Timings. the trace hooks can fire from
+// the transport's own goroutines (happy eyeballs dials in parallel), hence
+// the lock.
+type requestTimer struct {
+	mu    sync.Mutex
+	start time.Time
+	dns   time.Time
+	dial  time.Time
+	tls   time.Time
+	wrote time.Time
+	t     Timings
+}
+
+func newRequestTimer() *requestTimer {
+	return &requestTimer{start: time.Now()}
+}
+
+func (rt *requestTimer) attach(ctx context.Context) context.Context {
+	lock := func(fn func()) {
+		rt.mu.Lock()
+		fn()
+		rt.mu.Unlock()
+	}
+
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			lock(func() { rt.t.ReusedConn = info.Reused })
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			lock(func() { rt.dns = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			lock(func() { rt.t.DNS = time.Since(rt.dns) })
+		},
+		ConnectStart: func(string, string) {
+			lock(func() {
+				if rt.dial.IsZero() {
+					rt.dial = time.Now()
+				}
+			})
+		},
+		ConnectDone: func(_, _ string, err error) {
+			lock(func() {
+				if err == nil {
+					rt.t.Connect = time.Since(rt.dial)
+				}
+			})
+		},
+		TLSHandshakeStart: func() {
+			lock(func() { rt.tls = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			lock(func() { rt.t.TLS = time.Since(rt.tls) })
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			lock(func() { rt.wrote = time.Now() })
+		},
+		GotFirstResponseByte: func() {
+			lock(func() {
+				rt.t.TimeToFirstByte = time.Since(rt.start)
+				if !rt.wrote.IsZero() {
+					rt.t.Wait = time.Since(rt.wrote)
+				}
+			})
+		},
+	})
+}
+
+func (rt *requestTimer) report(url string, fn func(string, Timings)) {
+	rt.mu.Lock()
+	t := rt.t
+	rt.mu.Unlock()
+
+	t.Total = time.Since(rt.start)
+	fn(url, t)
+}
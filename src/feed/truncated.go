@@ -0,0 +1,17 @@
+package feed
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// isTruncated is true when the document just stops, as opposed to being
+// malformed somewhere in the middle
+func isTruncated(err error) bool {
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return syntaxErr.Msg == "unexpected EOF"
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,83 @@
+package feed
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/UW-UPL/harvest/src/feed/feedtest"
+)
+
+const wholeFeed = `<rss version="2.0"><channel><title>Cut</title>
+<item><title>First</title><link>https://example.com/1</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate></item>
+<item><title>Second</title><link>https://example.com/2</link><pubDate>Tue, 02 Jan 2024 10:00:00 GMT</pubDate></item>
+<item><title>Third</title><link>https://example.com/3</link><pubDate>Wed, 03 Jan 2024 10:00:00 GMT</pubDate></item>
+</channel></rss>`
+
+// cut partway through the third item
+var cutFeed = wholeFeed[:strings.Index(wholeFeed, "<title>Third")+12]
+
+func TestTruncatedFeed(t *testing.T) {
+	// the server sends all of what it has, it's just not a whole document
+	short := feedtest.NewServer([]byte(cutFeed))
+	defer short.Close()
+
+	// the connection goes away after half the promised body
+	dropped := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(wholeFeed)))
+		w.Write([]byte(cutFeed))
+		w.(http.Flusher).Flush()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer dropped.Close()
+
+	tests := []struct {
+		name      string
+		url       string
+		opts      []Option
+		truncated bool
+		titles    []string
+	}{
+		{"cut document", short.URL, nil, true, nil},
+		{"cut document, lenient", short.URL, []Option{WithLenient()}, false, []string{"First", "Second"}},
+		{"dropped connection", dropped.URL, nil, true, nil},
+		{"dropped connection, lenient", dropped.URL, []Option{WithLenient()}, false, []string{"First", "Second"}},
+	}
+	for _, tt := range tests {
+		posts, err := FetchFeed(tt.url, tt.opts...)
+		if got := errors.Is(err, ErrTruncatedFeed); got != tt.truncated {
+			t.Errorf("%s: err %v, want ErrTruncatedFeed: %v", tt.name, err, tt.truncated)
+		}
+		if !tt.truncated && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+
+		var titles []string
+		for _, post := range posts {
+			titles = append(titles, post.Title)
+		}
+		if strings.Join(titles, ",") != strings.Join(tt.titles, ",") {
+			t.Errorf("%s: got %q, want %q", tt.name, titles, tt.titles)
+		}
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	_, err := ParseFeed(strings.NewReader(cutFeed))
+	var pe *FeedParseError
+	if !errors.Is(err, ErrTruncatedFeed) || !errors.As(err, &pe) {
+		t.Fatalf("err %v, want a FeedParseError for ErrTruncatedFeed", err)
+	}
+
+	// broken in the middle isn't the same thing as cut off
+	_, err = ParseFeed(strings.NewReader(strings.Replace(wholeFeed, "</title>", "</titel>", 1)))
+	if err == nil || errors.Is(err, ErrTruncatedFeed) {
+		t.Errorf("mismatched tags: err %v, want a plain syntax error", err)
+	}
+}
@@ -1,43 +1,407 @@
 package feed
 
-import "time"
+import (
+	"encoding/xml"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
 
 type Config struct {
 	Feeds []string `toml:"feeds"`
 }
 
 type Item struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	PubDate     string `xml:"pubDate"`
-	Date        string `xml:"date"`
-	Published   string `xml:"published"`
-	Updated     string `xml:"updated"`
-	Author      string `xml:"author"`
-	Creator     string `xml:"creator"`
-	Description string `xml:"description"`
-	Content     string `xml:"content"`
-	Encoded     string `xml:"encoded"`
-	Summary     string `xml:"summary"`
+	Title     string  `xml:"title"`
+	Links     []Link  `xml:"link"`
+	GUID      RawGUID `xml:"guid"`
+	ID        string  `xml:"id"`
+	PubDate   string  `xml:"pubDate"`
+	Date      string  `xml:"date"`
+	Published string  `xml:"published"`
+	Updated   string  `xml:"updated"`
+	// atom 0.3's names for published & updated
+	Issued   string `xml:"issued"`
+	Modified string `xml:"modified"`
+	// <author> repeats in atom, & itunes:author lands here too
+	Authors []RawAuthor `xml:"author"`
+	// dc:creator can repeat for co-authored posts
+	Creators    []string  `xml:"creator"`
+	Description string    `xml:"description"`
+	Content     TypedText `xml:"content"`
+	Encoded     string    `xml:"encoded"`
+	// atom's <summary> and itunes:summary share a local name, and encoding/xml
+	// won't let a namespaced & un-namespaced field share one, so we keep all
+	// of them and sort them out by namespace later
+	Summaries []NamespacedText `xml:"summary"`
+	Subtitles []NamespacedText `xml:"subtitle"`
+
+	Categories []Category     `xml:"category"`
+	Enclosures []RawEnclosure `xml:"enclosure"`
+	Thumbnails []Thumbnail    `xml:"thumbnail"`
+	Source     RawSource      `xml:"source"`
+	// georss:point, or W3C geo's lat & long loose or in a geo:Point
+	GeoRSSPoint string   `xml:"http://www.georss.org/georss point"`
+	GeoLat      string   `xml:"http://www.w3.org/2003/01/geo/wgs84_pos# lat"`
+	GeoLong     string   `xml:"http://www.w3.org/2003/01/geo/wgs84_pos# long"`
+	GeoPoint    GeoPoint `xml:"http://www.w3.org/2003/01/geo/wgs84_pos# Point"`
+	// wordpress & friends link each post's own comment feed
+	CommentRss string `xml:"http://wellformedweb.org/CommentAPI/ commentRss"`
+	// podcasting 2.0 <podcast:transcript> & <podcast:chapters>, see podcast.go
+	Transcripts []RawTranscript `xml:"https://podcastindex.org/namespace/1.0 transcript"`
+	Chapters    RawChapters     `xml:"https://podcastindex.org/namespace/1.0 chapters"`
+
+	// Raw is the item's markup, only kept with WithRawItemXML (decodeItem fills it in)
+	Raw string `xml:"-"`
+}
+
+// isPermaLink defaults to true per the RSS spec, so empty counts as yes
+type RawGUID struct {
+	Text        string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr"`
+}
+
+// permalink is the guid as an article URL, or "" if it isn't one. a lot of
+// feeds leave isPermaLink off on guids that are nothing like URLs, so those
+// need to actually look like one too.
+func (g RawGUID) permalink() string {
+	if flag := strings.TrimSpace(g.IsPermaLink); flag != "" && !strings.EqualFold(flag, "true") {
+		return ""
+	}
+
+	guid := strings.TrimSpace(g.Text)
+	lower := strings.ToLower(guid)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		return ""
+	}
+	return guid
+}
+
+// media:thumbnail
+type Thumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+// RSS puts the label in the element body, atom uses term="", and
+// itunes:category (which also lands here) uses text=""
+type Category struct {
+	Text  string `xml:",chardata"`
+	Term  string `xml:"term,attr"`
+	Label string `xml:"text,attr"`
+	// RSS calls the taxonomy domain="", atom calls it scheme=""
+	Domain string `xml:"domain,attr"`
+	Scheme string `xml:"scheme,attr"`
+}
+
+func (c Category) Value() string {
+	for _, v := range []string{c.Text, c.Term, c.Label} {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// length stays a string here so a junk value can't fail the whole document
+type RawEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type Image struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+	// strings for the same reason as RawEnclosure.Length
+	Width  string `xml:"width"`
+	Height string `xml:"height"`
+}
+
+const (
+	itunesNS = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+	wfwNS    = "http://wellformedweb.org/CommentAPI/"
+	// podcasting 2.0
+	podcastNS = "https://podcastindex.org/namespace/1.0"
+)
+
+type NamespacedText struct {
+	XMLName xml.Name
+	TypedText
+}
+
+// TypedText is an element that might say what its text is, like atom's
+// <summary> & <content> with type="text", "html" or "xhtml"
+type TypedText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+var xhtmlDivRegex = regexp.MustCompile(`(?s)^\s*<(?:\w+:)?div\b[^>]*>(.*)</(?:\w+:)?div>\s*$`)
+
+// html is the element as HTML, ready for cleanHTML. atom says a missing type
+// means text, but so many feeds put HTML in there anyway that we only treat
+// it as text when it says so.
+func (t TypedText) html() string {
+	switch strings.ToLower(strings.TrimSpace(t.Type)) {
+	case "xhtml":
+		// the markup is inline, wrapped in a single xhtml <div>
+		if m := xhtmlDivRegex.FindStringSubmatch(t.Inner); m != nil {
+			return m[1]
+		}
+		return t.Inner
+	case "text", "text/plain":
+		// escaped so cleaning gives back exactly this, "<" and all
+		return html.EscapeString(t.Text)
+	}
+	return t.Text
+}
+
+// textIn returns the first non-empty element from namespace ns, or from any
+// namespace other than the itunes one when ns is empty
+func textIn(elems []NamespacedText, ns string) string {
+	for _, elem := range elems {
+		if ns == "" && elem.XMLName.Space == itunesNS || ns != "" && elem.XMLName.Space != ns {
+			continue
+		}
+		if text := elem.html(); strings.TrimSpace(text) != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// Link covers both RSS's <link>url</link> and the atom-style
+// <link rel="self" href="url"/>, which RSS feeds borrow all the time
+type Link struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+func (l Link) URL() string {
+	if l.Href != "" {
+		return strings.TrimSpace(l.Href)
+	}
+	return strings.TrimSpace(l.Text)
+}
+
+// RSS just has text, atom adds version="" and uri=""
+type Generator struct {
+	Text    string `xml:",chardata"`
+	Version string `xml:"version,attr"`
+	URI     string `xml:"uri,attr"`
+}
+
+func (g Generator) String() string {
+	name := strings.TrimSpace(g.Text)
+	version := strings.TrimSpace(g.Version)
+	if version == "" || strings.Contains(name, version) {
+		return name
+	}
+	return strings.TrimSpace(name + " " + version)
 }
 
 type Channel struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	Items       []Item `xml:"item"`
-	Entries     []Item `xml:"entry"`
+	Title          string    `xml:"title"`
+	Links          []Link    `xml:"link"`
+	Description    string    `xml:"description"`
+	Language       string    `xml:"language"`
+	PubDate        string    `xml:"pubDate"`
+	LastBuildDate  string    `xml:"lastBuildDate"`
+	Generator      Generator `xml:"generator"`
+	ManagingEditor RawAuthor `xml:"managingEditor"`
+	WebMaster      RawAuthor `xml:"webMaster"`
+	// itunes podcast bits, see podcast.go
+	ItunesAuthor   string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author"`
+	ItunesOwner    ItunesOwner `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd owner"`
+	ItunesExplicit string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd explicit"`
+	Categories     []Category  `xml:"category"`
+	Image          Image       `xml:"image"`
+	// RSS feeds borrowing atom:updated
+	Updated string `xml:"updated"`
+	// the parser streams items past these (see stream.go), they only fill
+	// up when a Feed is unmarshalled the plain way
+	Items   []Item `xml:"item"`
+	Entries []Item `xml:"entry"`
 }
 
 type Feed struct {
-	Channel Channel `xml:"channel"`
-	Entries []Item  `xml:"entry"`
+	// XMLName tells us rss, atom or rdf (RSS 1.0) apart
+	XMLName xml.Name
+	// only rss & atom 0.3 declare one, atom 1.0 & rdf go by namespace
+	Version string `xml:"version,attr"`
+	// atom says its language with xml:lang on the root
+	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	// relative URLs in the feed are relative to this, when it's there
+	Base string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	// Channel is the first of Channels, which is the only one nearly every
+	// feed has
+	Channel  Channel   `xml:"-"`
+	Channels []Channel `xml:"channel"`
+	// RSS 0.90 & 1.0 (rdf) put their items next to the channel, not in it
+	Items []Item `xml:"item"`
+	// atom keeps these at the top level instead of in a channel
+	Title      string     `xml:"title"`
+	Links      []Link     `xml:"link"`
+	Categories []Category `xml:"category"`
+	Generator  Generator  `xml:"generator"`
+	Icon       string     `xml:"icon"`
+	Logo       string     `xml:"logo"`
+	Updated    string     `xml:"updated"`
+	// atom 0.3's updated
+	Modified string `xml:"modified"`
+	Entries  []Item `xml:"entry"`
+}
+
+const (
+	atomNS = "http://www.w3.org/2005/Atom"
+	rdfNS  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+)
+
+// FeedInfo is what a feed says about itself, as opposed to its posts.
+type FeedInfo struct {
+	// Format is "rss", "atom" or "rdf", whatever the root element says
+	Format string
+	// Version is the declared version, e.g. "2.0", "0.91" or atom's "0.3".
+	// atom 1.0 & rdf don't declare one, so they get "1.0" from their namespace.
+	Version     string
+	Title       string
+	Link        string
+	Description string
+	// Language is the declared <language> (or atom's xml:lang), e.g. "en-us"
+	Language string
+	// SelfURL & HubURL come from rel="self" / rel="hub" links (WebSub)
+	SelfURL string
+	HubURL  string
+	// IconURL is atom's small square <icon>, same as Icon.URL
+	IconURL string
+	// LogoURL is atom's <logo> or the RSS <image>, same as Logo.URL
+	LogoURL string
+	// Icon & Logo are the same images with their intended sizes
+	Icon FeedImage
+	Logo FeedImage
+	// FaviconURL is the site's favicon, for showing something when the
+	// feed has no Icon or Logo: /favicon.ico at the root of Link, or with
+	// WithFaviconLookup whatever the homepage's <link rel="icon"> says
+	FaviconURL string
+	// Categories are the site-wide ones, not any single post's Tags
+	Categories []Tag
+	// channel-level dates, zero when missing or unparseable
+	PubDate       time.Time
+	LastBuildDate time.Time
+	// FeedUpdated is atom's feed-level <updated>, when the feed as a whole
+	// last changed. If it hasn't moved since the last poll, none of the
+	// items have either.
+	FeedUpdated time.Time
+	// Generator is whatever software claims to have built the feed, e.g. "WordPress 6.4"
+	Generator string
+	// ManagingEditor & WebMaster are RSS's contacts for editorial and
+	// technical problems respectively
+	ManagingEditor Author
+	WebMaster      Author
+	// Podcast is nil unless the feed has itunes channel tags
+	Podcast *PodcastInfo
+	// EstimatedUpdateInterval is the median gap between the newest dated
+	// posts, for deciding how often to poll. 0 with fewer than 3 dated posts.
+	EstimatedUpdateInterval time.Duration
+	// Fingerprint is a hash of every post's GUID & dates, the same for as
+	// long as the posts are. compare it with last poll's before bothering
+	// to diff the posts themselves. only filled in with WithFingerprint.
+	Fingerprint string
+	// UnhandledNamespaces maps each namespace harvest doesn't read to the
+	// element names the feed used from it. only filled in with WithNamespaceReport.
+	UnhandledNamespaces map[string][]string
+}
+
+// ImageURL picks the icon for small spots & the logo for big ones, falling
+// back to whichever one the feed actually has.
+func (fi FeedInfo) ImageURL(small bool) string {
+	if small && fi.IconURL != "" || fi.LogoURL == "" {
+		return fi.IconURL
+	}
+	return fi.LogoURL
 }
 
 type BlogPost struct {
-	Title   string
-	Link    string
-	Date    time.Time
-	Author  string
-	Summary string
+	Title string
+	Link  string
+	// CanonicalURL is the original article a syndicated post points back to
+	// with rel="canonical", only looked for with WithContentCanonical
+	CanonicalURL string
+	// GUID is the item's <guid> (RSS) or <id> (Atom), or its link as a last resort
+	GUID string
+	// Slug is a URL-safe version of Title, only filled in with WithSlugs
+	Slug string
+	// Date is the same as PublishedAt, kept around for existing callers
+	Date        time.Time
+	PublishedAt time.Time
+	// UpdatedAt falls back to PublishedAt when the feed doesn't say
+	UpdatedAt time.Time
+	Author    string
+	// Authors lists every credited author, Author is just the first one
+	Authors []string
+	// AuthorDetail is the first author with whatever profile link & email
+	// the feed gave. Unlike Author it's left empty when nobody is credited.
+	AuthorDetail Author
+	Summary      string
+	// Excerpt is a shorter cut of the same text as Summary, for list previews
+	Excerpt string
+	// SummaryHTML is Summary with the WithAllowedTags tags kept, safe to put
+	// straight into a page. empty without that option.
+	SummaryHTML string
+	// ContentLength is the full article's length in characters once cleaned,
+	// 0 with WithMetadataOnly
+	ContentLength int
+	// Language is the feed's declared language, or with WithLanguageDetection
+	// a guess from the post itself when the feed doesn't declare one
+	Language string
+	// Tags are the item's own <category> labels
+	Tags []Tag
+	// SourceTitle is the title of the feed the post came from
+	SourceTitle string
+	// SourceURL is the feed URL the post was fetched (or parsed) from
+	SourceURL string
+	// Origin is the feed the post was reposted from, if it came from an aggregator
+	Origin Origin
+	// Location is where the post is about (GeoRSS or W3C geo), nil if it doesn't say
+	Location *Location
+	// CommentFeedURL is the post's own comments feed (wfw:commentRss), if it has one
+	CommentFeedURL string
+	// Podcast has the episode's transcripts & chapters, nil if it has neither
+	Podcast *PodcastEpisode
+
+	Enclosures []Enclosure
+	// Image is the post's thumbnail, if it has one
+	Image string
+	// Preview is a link card built from the content, only with WithPreviews
+	Preview Preview
+
+	// RawXML is the item's original markup, only filled in with WithRawItemXML
+	RawXML string
+
+	// Warnings lists whatever was wrong with the item while parsing it, like
+	// an unparseable date. nil for a clean item.
+	Warnings []string
+
+	// whether PublishedAt is the feed's, not just the fetch time
+	dated bool
+}
+
+// Tag is one category label. Domain is the taxonomy it belongs to, if the
+// feed bothered to say.
+type Tag struct {
+	Label  string
+	Domain string
+}
+
+// Enclosure is an attached file, usually podcast audio.
+// Length is 0 when the feed leaves it out or puts something silly there.
+type Enclosure struct {
+	URL    string
+	Type   string
+	Length int64
 }
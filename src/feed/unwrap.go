@@ -0,0 +1,63 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"regexp"
+)
+
+var (
+	jsonpRegex  = regexp.MustCompile(`(?s)^[\w$.]+\s*\((.*)\)\s*;?$`)
+	preRegex    = regexp.MustCompile(`(?is)<pre\b[^>]*>(.*?)</pre>`)
+	htmlPrefix  = regexp.MustCompile(`(?i)^(?:<!doctype\s+html|<html\b)`)
+	quotedRegex = regexp.MustCompile(`(?s)^'(.*)'$`)
+)
+
+// unwrapFeed digs the XML back out of the creative ways some servers
+// deliver it: inside a JSONP callback, or escaped in an HTML <pre> for
+// "viewing". anything that already looks like XML comes back untouched.
+func unwrapFeed(body []byte) []byte {
+	trimmed := bytes.TrimSpace(body)
+
+	if m := jsonpRegex.FindSubmatch(trimmed); m != nil {
+		if inner := unwrapJSONP(bytes.TrimSpace(m[1])); inner != nil {
+			return inner
+		}
+	}
+
+	if htmlPrefix.Match(trimmed) {
+		if m := preRegex.FindSubmatch(trimmed); m != nil {
+			inner := bytes.TrimSpace([]byte(html.UnescapeString(string(m[1]))))
+			if bytes.HasPrefix(inner, []byte("<")) {
+				return inner
+			}
+		}
+	}
+
+	return body
+}
+
+// the callback's argument is either the XML as a string, or an object with
+// the XML in one of its fields
+func unwrapJSONP(arg []byte) []byte {
+	if m := quotedRegex.FindSubmatch(arg); m != nil {
+		return m[1]
+	}
+
+	var text string
+	if json.Unmarshal(arg, &text) == nil {
+		return []byte(text)
+	}
+
+	var obj map[string]any
+	if json.Unmarshal(arg, &obj) != nil {
+		return nil
+	}
+	for _, value := range obj {
+		if text, ok := value.(string); ok && bytes.HasPrefix(bytes.TrimSpace([]byte(text)), []byte("<")) {
+			return []byte(text)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package feed
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestLenientUnwrapping(t *testing.T) {
+	const rss = `<rss version="2.0"><channel><title>Wrapped</title><item><title>Post</title><link>https://wrapped.example.com/1</link><description>Tom &amp; Jerry</description></item></channel></rss>`
+	read := func(name string) string {
+		body, err := os.ReadFile("testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"jsonp object", read("jsonp_wrapped.js")},
+		{"pre block", read("pre_wrapped.html")},
+		{"jsonp string", "cb(" + `"<rss version=\"2.0\"><channel><title>Wrapped</title><item><title>Post</title><link>https://wrapped.example.com/1</link><description>Tom &amp; Jerry</description></item></channel></rss>"` + ")"},
+		{"jsonp single quotes", "window.feeds.load('" + rss + "');"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseFeed(bytes.NewReader([]byte(tt.body))); err == nil {
+			t.Errorf("%s: parsed without WithLenient", tt.name)
+		}
+
+		posts, err := ParseFeed(bytes.NewReader([]byte(tt.body)), WithLenient())
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(posts) != 1 || posts[0].SourceTitle != "Wrapped" || posts[0].Link != "https://wrapped.example.com/1" || posts[0].Summary != "Tom & Jerry" {
+			t.Errorf("%s: got %+v", tt.name, posts)
+		}
+	}
+
+	// a feed that's fine already comes through WithLenient untouched
+	if got := unwrapFeed([]byte(rss)); string(got) != rss {
+		t.Errorf("plain feed changed to %q", got)
+	}
+}
@@ -0,0 +1,75 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// how many feeds ValidateFeeds checks at a time. an OPML import can be
+// hundreds of feeds, and they shouldn't all go out at once.
+const validateWorkers = 8
+
+// FeedInfoOrError is one feed's result from ValidateFeeds: its metadata if
+// it works, or why it doesn't.
+type FeedInfoOrError struct {
+	Info FeedInfo
+	Err  error
+}
+
+func ValidateFeed(ctx context.Context, url string, opts ...Option) (FeedInfo, error) {
+	return NewFetcher(opts...).ValidateFeed(ctx, url)
+}
+
+// ValidateFeed fetches & parses url to check that it's a working feed,
+// without bothering to clean up any posts. A feed with no posts yet still
+// counts as working.
+func (f *Fetcher) ValidateFeed(ctx context.Context, url string) (FeedInfo, error) {
+	lo := *f.opts
+	lo.metadataOnly = true
+
+	info, _, err := f.fetchFeed(ctx, url, &lo)
+	if errors.Is(err, ErrNoItemsFound) {
+		return info, nil
+	}
+	return info, err
+}
+
+func ValidateFeeds(ctx context.Context, urls []string, opts ...Option) map[string]FeedInfoOrError {
+	return NewFetcher(opts...).ValidateFeeds(ctx, urls)
+}
+
+// ValidateFeeds runs ValidateFeed on every URL, a few at a time, e.g. to
+// review an OPML import before subscribing. Once ctx is done, whatever
+// hasn't been checked yet gets ctx's error.
+func (f *Fetcher) ValidateFeeds(ctx context.Context, urls []string) map[string]FeedInfoOrError {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = make(map[string]FeedInfoOrError, len(urls))
+		sem     = make(chan struct{}, validateWorkers)
+	)
+
+	for _, url := range urls {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+
+			var result FeedInfoOrError
+			select {
+			case sem <- struct{}{}:
+				result.Info, result.Err = f.ValidateFeed(ctx, url)
+				<-sem
+			case <-ctx.Done():
+				result.Err = ctx.Err()
+			}
+
+			mu.Lock()
+			results[url] = result
+			mu.Unlock()
+		}(url)
+	}
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,269 @@
+package markdown
+
+import (
+	"crypto/sha256"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed"
+)
+
+const atomNS = "http://www.w3.org/2005/Atom"
+
+// FeedMeta is the feed-level part of a RenderAtom feed.
+type FeedMeta struct {
+	Title    string
+	Subtitle string
+	// ID is the feed's permanent atom id, SelfURL (then Link) if empty
+	ID string
+	// Link is the page the feed is for, SelfURL where it's published
+	Link    string
+	SelfURL string
+	// Author is credited as the feed's author. without one every entry gets
+	// its own (atom wants somebody on each), falling back to its source's title.
+	Author string
+	// Updated defaults to the newest post's date
+	Updated time.Time
+}
+
+// the encoding/xml side of things, which takes care of all the escaping
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length string `xml:"length,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Text string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term   string `xml:"term,attr"`
+	Scheme string `xml:"scheme,attr,omitempty"`
+}
+
+type atomSource struct {
+	ID    string     `xml:"id"`
+	Title string     `xml:"title,omitempty"`
+	Links []atomLink `xml:"link"`
+}
+
+type atomEntry struct {
+	XMLName    xml.Name       `xml:"entry"`
+	ID         string         `xml:"id"`
+	Title      atomText       `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published,omitempty"`
+	Authors    []atomPerson   `xml:"author"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary"`
+	Content    *atomText      `xml:"content"`
+	Source     *atomSource    `xml:"source"`
+}
+
+// one of the feed-level elements that go out before the entries
+type atomElement struct {
+	name  string
+	value any
+}
+
+// RenderAtom writes posts out as an Atom 1.0 feed, e.g. to republish a
+// merged FetchAllFeeds. posts go in the order given. Every entry's id is its
+// GUID or link when that's a URI (see entryID), & each keeps a <source>
+// pointing back at its feed.
+func RenderAtom(w io.Writer, meta FeedMeta, posts []feed.BlogPost) error {
+	id := firstNonEmpty(meta.ID, meta.SelfURL, meta.Link)
+	if id == "" {
+		return errors.New("writing atom: FeedMeta needs an ID, SelfURL or Link")
+	}
+
+	updated := meta.Updated
+	if updated.IsZero() {
+		for _, post := range posts {
+			if t := postUpdated(post); t.After(updated) {
+				updated = t
+			}
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+
+	sw := newStreamWriter(w)
+	sw.WriteString(xml.Header)
+	enc := xml.NewEncoder(sw)
+	enc.Indent("", "  ")
+
+	start := xml.StartElement{Name: xml.Name{Local: "feed"}, Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: atomNS}}}
+	if err := enc.EncodeToken(start); err != nil {
+		return fmt.Errorf("writing atom: %w", err)
+	}
+
+	header := []atomElement{
+		{"id", id},
+		{"title", atomText{Type: "text", Text: meta.Title}},
+		{"updated", updated.Format(time.RFC3339)},
+		{"generator", "harvest"},
+	}
+	if meta.Subtitle != "" {
+		header = append(header, atomElement{"subtitle", atomText{Type: "text", Text: meta.Subtitle}})
+	}
+	if meta.Author != "" {
+		header = append(header, atomElement{"author", atomPerson{Name: meta.Author}})
+	}
+	if meta.Link != "" {
+		header = append(header, atomElement{"link", atomLink{Href: meta.Link, Rel: "alternate"}})
+	}
+	if meta.SelfURL != "" {
+		header = append(header, atomElement{"link", atomLink{Href: meta.SelfURL, Rel: "self", Type: "application/atom+xml"}})
+	}
+	for _, h := range header {
+		if err := enc.EncodeElement(h.value, xml.StartElement{Name: xml.Name{Local: h.name}}); err != nil {
+			return fmt.Errorf("writing atom: %w", err)
+		}
+	}
+
+	for i, post := range posts {
+		if err := enc.Encode(atomEntryFor(post, meta, updated)); err != nil {
+			return fmt.Errorf("writing atom: %w", err)
+		}
+
+		if (i+1)%flushEvery == 0 {
+			if err := enc.Flush(); err != nil {
+				return fmt.Errorf("writing atom: %w", err)
+			}
+			if err := sw.flush(); err != nil {
+				return fmt.Errorf("writing atom: %w", err)
+			}
+		}
+	}
+
+	if err := enc.EncodeToken(start.End()); err != nil {
+		return fmt.Errorf("writing atom: %w", err)
+	}
+	if err := enc.Flush(); err != nil {
+		return fmt.Errorf("writing atom: %w", err)
+	}
+	sw.WriteString("\n")
+	if err := sw.flush(); err != nil {
+		return fmt.Errorf("writing atom: %w", err)
+	}
+	return nil
+}
+
+func atomEntryFor(post feed.BlogPost, meta FeedMeta, feedUpdated time.Time) atomEntry {
+	entry := atomEntry{
+		ID:    entryID(post),
+		Title: atomText{Type: "text", Text: post.Title},
+	}
+
+	updated := postUpdated(post)
+	if updated.IsZero() {
+		updated = feedUpdated
+	}
+	entry.Updated = updated.Format(time.RFC3339)
+	entry.Published = rfc3339(post.PublishedAt)
+
+	authors := post.Authors
+	if len(authors) == 0 && post.Author != "" {
+		authors = []string{post.Author}
+	}
+	for _, author := range authors {
+		entry.Authors = append(entry.Authors, atomPerson{Name: author})
+	}
+	if len(entry.Authors) == 0 && meta.Author == "" {
+		entry.Authors = []atomPerson{{Name: firstNonEmpty(post.SourceTitle, "unknown")}}
+	}
+
+	if post.Link != "" {
+		entry.Links = append(entry.Links, atomLink{Href: post.Link, Rel: "alternate"})
+	}
+	for _, enclosure := range post.Enclosures {
+		link := atomLink{Href: enclosure.URL, Rel: "enclosure", Type: enclosure.Type}
+		if enclosure.Length > 0 {
+			link.Length = strconv.FormatInt(enclosure.Length, 10)
+		}
+		entry.Links = append(entry.Links, link)
+	}
+
+	for _, tag := range post.Tags {
+		entry.Categories = append(entry.Categories, atomCategory{Term: tag.Label, Scheme: tag.Domain})
+	}
+
+	if post.Summary != "" {
+		entry.Summary = &atomText{Type: "text", Text: post.Summary}
+	}
+	// SummaryHTML is already sanitized, so it can go out as markup
+	if post.SummaryHTML != "" {
+		entry.Content = &atomText{Type: "html", Text: post.SummaryHTML}
+	}
+
+	if post.SourceURL != "" {
+		entry.Source = &atomSource{
+			ID:    post.SourceURL,
+			Title: post.SourceTitle,
+			Links: []atomLink{{Href: post.SourceURL, Rel: "self"}},
+		}
+	}
+
+	return entry
+}
+
+// atom ids have to be there, stay put & be IRIs. a GUID that's already an
+// absolute URI goes as is, a bare one ("123", WordPress' "?p=42"...) gets
+// hashed with its feed's URL into a urn, since it's only unique in there.
+// no GUID means the link, & neither a hash of what the post does have.
+func entryID(post feed.BlogPost) string {
+	if isAbsoluteURI(post.GUID) {
+		return post.GUID
+	}
+	if post.GUID != "" {
+		return hashID(post.SourceURL, post.GUID)
+	}
+	if isAbsoluteURI(post.Link) {
+		return post.Link
+	}
+	return hashID(post.SourceURL, post.Link, post.Title, rfc3339(post.PublishedAt))
+}
+
+func isAbsoluteURI(s string) bool {
+	if s == "" || strings.ContainsAny(s, " \t\r\n") {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && (u.Opaque != "" || u.Host != "" || u.Path != "")
+}
+
+func hashID(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return fmt.Sprintf("urn:sha256:%x", sum)
+}
+
+func postUpdated(post feed.BlogPost) time.Time {
+	if post.UpdatedAt.After(post.PublishedAt) {
+		return post.UpdatedAt
+	}
+	return post.PublishedAt
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
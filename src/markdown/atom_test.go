@@ -0,0 +1,76 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed"
+)
+
+func TestRenderAtomRoundTrip(t *testing.T) {
+	published := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	posts := []feed.BlogPost{
+		{Title: "Absolute GUID", GUID: "https://example.com/?p=1", Link: "https://example.com/one", SourceURL: "https://example.com/feed", PublishedAt: published},
+		{Title: "Bare GUID", GUID: "123", Link: "https://example.com/two", SourceURL: "https://example.com/feed", PublishedAt: published},
+		{Title: "Tag GUID", GUID: "tag:example.com,2024:3", SourceURL: "https://example.com/feed", PublishedAt: published},
+		{Title: "Link only", Link: "https://example.com/four", SourceURL: "https://example.com/feed", PublishedAt: published},
+		{Title: "Nothing <but> & a title", SourceURL: "https://example.com/feed", PublishedAt: published},
+	}
+
+	var buf bytes.Buffer
+	meta := FeedMeta{Title: "Merged", SelfURL: "https://planet.example.com/atom.xml"}
+	if err := RenderAtom(&buf, meta, posts); err != nil {
+		t.Fatal(err)
+	}
+
+	info, parsed, err := feed.ParseFeedInfo(bytes.NewReader(buf.Bytes()), feed.WithPreserveOrder())
+	if err != nil {
+		t.Fatalf("re-parsing: %v\n%s", err, buf.String())
+	}
+	if info.Format != "atom" || info.Title != "Merged" {
+		t.Errorf("got a %s feed titled %q", info.Format, info.Title)
+	}
+	if len(parsed) != len(posts) {
+		t.Fatalf("got %d entries back, want %d", len(parsed), len(posts))
+	}
+
+	tests := []struct {
+		id   string
+		link string
+	}{
+		{"https://example.com/?p=1", "https://example.com/one"},
+		{"urn:sha256:", "https://example.com/two"},
+		{"tag:example.com,2024:3", ""},
+		{"https://example.com/four", "https://example.com/four"},
+		{"urn:sha256:", ""},
+	}
+	ids := make(map[string]bool)
+	for i, tt := range tests {
+		got := parsed[i]
+		if !strings.HasPrefix(got.GUID, tt.id) {
+			t.Errorf("%s: id %q, want %q", posts[i].Title, got.GUID, tt.id)
+		}
+		if !isAbsoluteURI(got.GUID) {
+			t.Errorf("%s: id %q isn't an IRI", posts[i].Title, got.GUID)
+		}
+		if got.Link != tt.link {
+			t.Errorf("%s: link %q, want %q", posts[i].Title, got.Link, tt.link)
+		}
+		if got.Title != posts[i].Title {
+			t.Errorf("title %q came back as %q", posts[i].Title, got.Title)
+		}
+		ids[got.GUID] = true
+	}
+	if len(ids) != len(posts) {
+		t.Errorf("ids aren't unique: %v", ids)
+	}
+
+	// the same bare GUID in another feed is another entry
+	other := posts[1]
+	other.SourceURL = "https://other.example.com/feed"
+	if entryID(other) == entryID(posts[1]) {
+		t.Error("bare GUIDs from different feeds got the same id")
+	}
+}
@@ -0,0 +1,97 @@
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed"
+)
+
+type DigestOptions struct {
+	// Location decides where one day ends & the next begins. nil means UTC.
+	Location *time.Location
+	// Now, when set, labels its own day "Today" & the one before "Yesterday",
+	// both worked out in Location
+	Now time.Time
+}
+
+// dayHeading is the heading for date, which is already in loc
+func (opts DigestOptions) dayHeading(date time.Time, loc *time.Location) string {
+	if !opts.Now.IsZero() {
+		now := opts.Now.In(loc)
+		switch date.Format(time.DateOnly) {
+		case now.Format(time.DateOnly):
+			return "Today"
+		case now.AddDate(0, 0, -1).Format(time.DateOnly):
+			return "Yesterday"
+		}
+	}
+	return date.Format("Monday, Jan 2")
+}
+
+// RenderDigest lays posts out as plain text under one heading per calendar
+// day ("Monday, Jan 6"), oldest day first, for things like email digests.
+func RenderDigest(posts []feed.BlogPost, opts DigestOptions) string {
+	var b strings.Builder
+	WriteDigest(&b, posts, opts) // a strings.Builder never fails
+	return b.String()
+}
+
+// WriteDigest is RenderDigest streaming to w instead of building a string.
+func WriteDigest(w io.Writer, posts []feed.BlogPost, opts DigestOptions) error {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	// don't reorder the caller's slice
+	sorted := append([]feed.BlogPost(nil), posts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	sw := newStreamWriter(w)
+	var lastDay string
+
+	for i, post := range sorted {
+		date := post.Date.In(loc)
+		day := date.Format(time.DateOnly)
+		if day != lastDay {
+			if lastDay != "" {
+				sw.WriteString("\n")
+			}
+			fmt.Fprintf(sw, "%s\n\n", opts.dayHeading(date, loc))
+			lastDay = day
+		}
+
+		fmt.Fprintf(sw, "- %s", post.Title)
+		if source := digestSource(post); source != "" {
+			fmt.Fprintf(sw, " (%s)", source)
+		}
+		sw.WriteString("\n")
+		if post.Summary != "" {
+			fmt.Fprintf(sw, "  %s\n", post.Summary)
+		}
+
+		if (i+1)%flushEvery == 0 {
+			if err := sw.flush(); err != nil {
+				return fmt.Errorf("writing digest: %w", err)
+			}
+		}
+	}
+
+	if err := sw.flush(); err != nil {
+		return fmt.Errorf("writing digest: %w", err)
+	}
+	return nil
+}
+
+func digestSource(post feed.BlogPost) string {
+	if post.SourceTitle != "" {
+		return post.SourceTitle
+	}
+	return post.Author
+}
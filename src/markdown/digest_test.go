@@ -0,0 +1,69 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed"
+)
+
+func TestDigestTimezone(t *testing.T) {
+	chicago := time.FixedZone("CST", -6*60*60)
+	posts := []feed.BlogPost{
+		// 21:30 on Saturday the 9th in Chicago, already Sunday in UTC
+		{Title: "Late night", SourceTitle: "Blog", Date: time.Date(2024, 3, 10, 3, 30, 0, 0, time.UTC)},
+		{Title: "Sunday lunch", SourceTitle: "Blog", Date: time.Date(2024, 3, 10, 18, 0, 0, 0, time.UTC)},
+	}
+
+	tests := []struct {
+		name string
+		opts DigestOptions
+		want string
+	}{
+		{
+			"utc", DigestOptions{},
+			"Sunday, Mar 10\n\n- Late night (Blog)\n- Sunday lunch (Blog)\n",
+		},
+		{
+			"chicago", DigestOptions{Location: chicago},
+			"Saturday, Mar 9\n\n- Late night (Blog)\n\nSunday, Mar 10\n\n- Sunday lunch (Blog)\n",
+		},
+		{
+			// 01:00 UTC on the 11th is still the 10th in Chicago
+			"today in chicago", DigestOptions{Location: chicago, Now: time.Date(2024, 3, 11, 1, 0, 0, 0, time.UTC)},
+			"Yesterday\n\n- Late night (Blog)\n\nToday\n\n- Sunday lunch (Blog)\n",
+		},
+		{
+			"today in utc", DigestOptions{Now: time.Date(2024, 3, 11, 1, 0, 0, 0, time.UTC)},
+			"Yesterday\n\n- Late night (Blog)\n- Sunday lunch (Blog)\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := RenderDigest(posts, tt.opts); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenderLocation(t *testing.T) {
+	post := feed.BlogPost{Title: "Late night", Link: "https://example.com/1", Date: time.Date(2024, 3, 10, 3, 30, 0, 0, time.UTC)}
+	tests := []struct {
+		name string
+		opts RenderOptions
+		want string
+	}{
+		{"feed's own zone", RenderOptions{}, "- [Late night](https://example.com/1) (2024-03-10)\n"},
+		{"chicago", RenderOptions{Location: time.FixedZone("CST", -6*60*60)}, "- [Late night](https://example.com/1) (2024-03-09)\n"},
+		{"chicago with time", RenderOptions{Location: time.FixedZone("CST", -6*60*60), DateFormat: "2006-01-02 15:04"}, "- [Late night](https://example.com/1) (2024-03-09 21:30)\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := RenderMarkdown(&buf, []feed.BlogPost{post}, tt.opts); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package markdown
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed"
+)
+
+// one line of JSONL. unlike postOut this keeps full timestamps, since log
+// tooling wants to sort & window on them.
+type jsonlPost struct {
+	Title       string   `json:"title"`
+	Link        string   `json:"link"`
+	GUID        string   `json:"guid,omitempty"`
+	Published   string   `json:"published,omitempty"`
+	Updated     string   `json:"updated,omitempty"`
+	Author      string   `json:"author,omitempty"`
+	Summary     string   `json:"summary,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	SourceTitle string   `json:"source_title,omitempty"`
+	SourceURL   string   `json:"source_url,omitempty"`
+	Image       string   `json:"image,omitempty"`
+}
+
+// JSONLWriter writes posts one JSON object per line as they come in, e.g.
+// from feed.ParseFeedFunc, so nothing has to hold the whole list.
+type JSONLWriter struct {
+	enc *json.Encoder
+}
+
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	enc := json.NewEncoder(w)
+	// summaries are full of & and <, & escaping them helps nobody reading a log
+	enc.SetEscapeHTML(false)
+	return &JSONLWriter{enc: enc}
+}
+
+// Write emits post as one line. Encoder.Encode always ends with a newline
+// & never puts one inside, which is all JSONL asks for.
+func (jw *JSONLWriter) Write(post feed.BlogPost) error {
+	out := jsonlPost{
+		Title:       post.Title,
+		Link:        post.Link,
+		GUID:        post.GUID,
+		Published:   rfc3339(post.PublishedAt),
+		Updated:     rfc3339(post.UpdatedAt),
+		Author:      post.Author,
+		Summary:     post.Summary,
+		SourceTitle: post.SourceTitle,
+		SourceURL:   post.SourceURL,
+		Image:       post.Image,
+	}
+	for _, tag := range post.Tags {
+		out.Tags = append(out.Tags, tag.Label)
+	}
+
+	if err := jw.enc.Encode(out); err != nil {
+		return fmt.Errorf("writing jsonl: %w", err)
+	}
+	return nil
+}
+
+// WriteJSONL writes every post in posts as JSON Lines.
+func WriteJSONL(w io.Writer, posts []feed.BlogPost) error {
+	sw := newStreamWriter(w)
+	jw := NewJSONLWriter(sw)
+
+	for i, post := range posts {
+		if err := jw.Write(post); err != nil {
+			return err
+		}
+
+		if (i+1)%flushEvery == 0 {
+			if err := sw.flush(); err != nil {
+				return fmt.Errorf("writing jsonl: %w", err)
+			}
+		}
+	}
+
+	if err := sw.flush(); err != nil {
+		return fmt.Errorf("writing jsonl: %w", err)
+	}
+	return nil
+}
+
+func rfc3339(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,161 @@
+package markdown
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed"
+)
+
+type RenderOptions struct {
+	// DateFormat is a time layout for post dates, time.DateOnly if empty
+	DateFormat string
+	// Location is the zone dates are shown in. nil leaves each date in
+	// whatever zone its feed wrote it in.
+	Location *time.Location
+	// HideSourceAuthor leaves the author off posts whose author is just the
+	// feed's own title (what getAuthor falls back to with nobody credited),
+	// which is noise in a single-source view
+	HideSourceAuthor bool
+}
+
+func (opts RenderOptions) author(post feed.BlogPost) string {
+	author := strings.TrimSpace(post.Author)
+	if opts.HideSourceAuthor && strings.EqualFold(author, strings.TrimSpace(post.SourceTitle)) {
+		return ""
+	}
+	return author
+}
+
+func (opts RenderOptions) date(post feed.BlogPost) string {
+	layout := opts.DateFormat
+	if layout == "" {
+		layout = time.DateOnly
+	}
+
+	date := post.Date
+	if opts.Location != nil {
+		date = date.In(opts.Location)
+	}
+	return date.Format(layout)
+}
+
+// how many posts we buffer before pushing them out to the underlying writer
+const flushEvery = 50
+
+// streamWriter buffers writes but regularly pushes them all the way through,
+// including through an http.ResponseWriter or gzip.Writer, so nothing has to
+// hold the whole document in memory
+type streamWriter struct {
+	*bufio.Writer
+	dst io.Writer
+}
+
+func newStreamWriter(w io.Writer) *streamWriter {
+	return &streamWriter{Writer: bufio.NewWriter(w), dst: w}
+}
+
+func (sw *streamWriter) flush() error {
+	if err := sw.Writer.Flush(); err != nil {
+		return err
+	}
+
+	switch dst := sw.dst.(type) {
+	case interface{ Flush() error }:
+		return dst.Flush()
+	case http.Flusher:
+		dst.Flush()
+	}
+	return nil
+}
+
+// the same as the feed package's: what would turn feed text into markdown
+// of its own. a newline would end the list item.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`,
+	"\r\n", " ", "\n", " ", "\r", " ",
+)
+
+// what would end a link's (destination) early
+var linkEscaper = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E")
+
+// RenderMarkdown writes posts as a markdown list to w. titles & authors are
+// escaped, & a post without a web link is listed without one.
+func RenderMarkdown(w io.Writer, posts []feed.BlogPost, opts RenderOptions) error {
+	sw := newStreamWriter(w)
+
+	for i, post := range posts {
+		title := markdownEscaper.Replace(post.Title)
+		if isWebLink(post.Link) {
+			fmt.Fprintf(sw, "- [%s](%s)", title, linkEscaper.Replace(strings.TrimSpace(post.Link)))
+		} else {
+			fmt.Fprintf(sw, "- %s", title)
+		}
+		if author := opts.author(post); author != "" {
+			fmt.Fprintf(sw, " by %s", markdownEscaper.Replace(author))
+		}
+		fmt.Fprintf(sw, " (%s)\n", opts.date(post))
+		if post.Summary != "" {
+			fmt.Fprintf(sw, "  %s\n", post.Summary)
+		}
+
+		if (i+1)%flushEvery == 0 {
+			if err := sw.flush(); err != nil {
+				return fmt.Errorf("writing markdown: %w", err)
+			}
+		}
+	}
+
+	if err := sw.flush(); err != nil {
+		return fmt.Errorf("writing markdown: %w", err)
+	}
+	return nil
+}
+
+// RenderHTML writes posts as an HTML fragment (a <ul>) to w, flushing as it
+// goes so a big list starts arriving before it's finished.
+func RenderHTML(w io.Writer, posts []feed.BlogPost, opts RenderOptions) error {
+	sw := newStreamWriter(w)
+
+	sw.WriteString("<ul class=\"posts\">\n")
+	for i, post := range posts {
+		sw.WriteString("  <li>")
+		title := html.EscapeString(post.Title)
+		if isWebLink(post.Link) {
+			fmt.Fprintf(sw, "<a href=\"%s\">%s</a>", html.EscapeString(post.Link), title)
+		} else {
+			sw.WriteString(title)
+		}
+		if author := opts.author(post); author != "" {
+			fmt.Fprintf(sw, " <span class=\"author\">%s</span>", html.EscapeString(author))
+		}
+		fmt.Fprintf(sw, " <time>%s</time>", html.EscapeString(opts.date(post)))
+		if post.Summary != "" {
+			fmt.Fprintf(sw, "<p>%s</p>", html.EscapeString(post.Summary))
+		}
+		sw.WriteString("</li>\n")
+
+		if (i+1)%flushEvery == 0 {
+			if err := sw.flush(); err != nil {
+				return fmt.Errorf("writing html: %w", err)
+			}
+		}
+	}
+	sw.WriteString("</ul>\n")
+
+	if err := sw.flush(); err != nil {
+		return fmt.Errorf("writing html: %w", err)
+	}
+	return nil
+}
+
+// escaping won't save us from a javascript: link, so only real web links get an href
+func isWebLink(link string) bool {
+	lower := strings.ToLower(strings.TrimSpace(link))
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
@@ -0,0 +1,37 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/UW-UPL/harvest/src/feed"
+)
+
+func TestRenderMarkdownEscaping(t *testing.T) {
+	date := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		post feed.BlogPost
+		want string
+	}{
+		{"plain", feed.BlogPost{Title: "Hello", Link: "https://example.com/1"}, "- [Hello](https://example.com/1) (2024-01-02)\n"},
+		{"markdown in title", feed.BlogPost{Title: "a *b* _c_ `d` [e] \\f", Link: "https://example.com/1"}, "- [a \\*b\\* \\_c\\_ \\`d\\` \\[e\\] \\\\f](https://example.com/1) (2024-01-02)\n"},
+		{"link breaking out", feed.BlogPost{Title: "x](https://evil.example)[y", Link: "https://example.com/1"}, "- [x\\](https://evil.example)\\[y](https://example.com/1) (2024-01-02)\n"},
+		{"parens in link", feed.BlogPost{Title: "Wiki", Link: "https://example.com/a_(b) c"}, "- [Wiki](https://example.com/a_%28b%29%20c) (2024-01-02)\n"},
+		{"javascript link", feed.BlogPost{Title: "Click", Link: "javascript:alert(1)"}, "- Click (2024-01-02)\n"},
+		{"no link", feed.BlogPost{Title: "Note"}, "- Note (2024-01-02)\n"},
+		{"newline in title", feed.BlogPost{Title: "one\ntwo", Link: "https://example.com/1"}, "- [one two](https://example.com/1) (2024-01-02)\n"},
+		{"author", feed.BlogPost{Title: "T", Link: "https://example.com/1", Author: "_me_"}, "- [T](https://example.com/1) by \\_me\\_ (2024-01-02)\n"},
+	}
+	for _, tt := range tests {
+		tt.post.Date = date
+		var buf bytes.Buffer
+		if err := RenderMarkdown(&buf, []feed.BlogPost{tt.post}, RenderOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}